@@ -0,0 +1,272 @@
+package goup
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Extractor extracts Go release archive to target path, removing the top
+// level "go" directory
+type Extractor interface {
+	Extract(src io.ReaderAt, size int64, targetPath string, progCback func(format string, arg ...interface{})) error
+}
+
+// TarGzExtractor extracts .tar.gz archives
+type TarGzExtractor struct{}
+
+// ZipExtractor extracts .zip archives
+type ZipExtractor struct{}
+
+// ArchiveFormat returns the extension of Go release archive for an OS
+func ArchiveFormat(goos string) string {
+	if goos == "windows" {
+		return "zip"
+	}
+	return "tar.gz"
+}
+
+// ExtractorFor returns Extractor by archive file name extension
+func ExtractorFor(name string) (Extractor, error) {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return TarGzExtractor{}, nil
+	case strings.HasSuffix(lower, ".zip"):
+		return ZipExtractor{}, nil
+	}
+	return nil, fmt.Errorf("Unknown archive format: %s", name)
+}
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zipMagic  = []byte("PK\x03\x04")
+)
+
+// DetectExtractor returns Extractor by magic bytes at the start of archive
+func DetectExtractor(src io.ReaderAt) (Extractor, error) {
+	magic := make([]byte, 4)
+	n, err := src.ReadAt(magic, 0)
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "Cannot read archive")
+	}
+	switch {
+	case bytes.HasPrefix(magic[:n], gzipMagic):
+		return TarGzExtractor{}, nil
+	case bytes.HasPrefix(magic[:n], zipMagic):
+		return ZipExtractor{}, nil
+	}
+	return nil, errors.New("Unknown archive format")
+}
+
+// ExtractArchive extracts Go release archive of any supported format to
+// targetPath. size is the archive size reported by download, or negative if
+// unknown, and the archive is rejected when srcFile has a different size.
+func ExtractArchive(srcFile *os.File, size int64, targetPath string, progCback func(format string, arg ...interface{})) error {
+	fi, err := srcFile.Stat()
+	if err != nil {
+		return errors.Wrap(err, "Cannot read archive size")
+	}
+	if size >= 0 && size != fi.Size() {
+		return errors.Errorf("Archive has %d bytes, %d bytes expected", fi.Size(), size)
+	}
+	extractor, err := DetectExtractor(srcFile)
+	if err != nil {
+		return err
+	}
+	return extractor.Extract(srcFile, fi.Size(), targetPath, progCback)
+}
+
+// Extract implements Extractor
+func (TarGzExtractor) Extract(src io.ReaderAt, size int64, targetPath string, progCback func(format string, arg ...interface{})) error {
+	gzFile, err := gzip.NewReader(io.NewSectionReader(src, 0, size))
+	if err != nil {
+		return errors.Wrap(err, "Error opening GZip archive")
+	}
+	return extractTar(gzFile, targetPath, true, progCback)
+}
+
+// Extract implements Extractor
+func (ZipExtractor) Extract(src io.ReaderAt, size int64, targetPath string, progCback func(format string, arg ...interface{})) error {
+	zipFile, err := zip.NewReader(src, size)
+	if err != nil {
+		return errors.Wrap(err, "Error opening zip archive")
+	}
+
+	for _, f := range zipFile.File {
+		name, ok := stripFirstDir(f.Name)
+		if !ok {
+			continue
+		}
+		dstPath, err := safeJoin(targetPath, name)
+		if err != nil {
+			return err
+		}
+		if f.FileInfo().IsDir() {
+			if err = os.MkdirAll(dstPath, 0755); err != nil {
+				return errors.Wrap(err, "Cannot create directory")
+			}
+			continue
+		}
+		progCback("Extracting %s...\n", dstPath)
+		err = os.MkdirAll(filepath.Dir(dstPath), 0755)
+		if err != nil {
+			return errors.Wrap(err, "Cannot create directory")
+		}
+		mode := f.Mode().Perm()
+		if mode == 0 {
+			mode = 0644
+		}
+		os.Remove(dstPath)
+		dstFile, err := os.OpenFile(dstPath, os.O_CREATE|os.O_WRONLY|os.O_EXCL, mode)
+		if err != nil {
+			return errors.Wrap(err, "Cannot create file")
+		}
+		afr, err := f.Open()
+		if err != nil {
+			dstFile.Close()
+			return errors.Wrap(err, "Cannot open file")
+		}
+		_, err = io.Copy(dstFile, afr)
+		afr.Close()
+		if closeErr := dstFile.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return errors.Wrap(err, "Cannot write data")
+		}
+		os.Chtimes(dstPath, f.Modified, f.Modified)
+	}
+	return nil
+}
+
+// safeJoin joins archive entry name to root, rejecting names which would
+// escape root
+func safeJoin(root, name string) (string, error) {
+	name = path.Clean(strings.Replace(name, "\\", "/", -1))
+	if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("Illegal path in archive: %s", name)
+	}
+	return filepath.Join(root, filepath.FromSlash(name)), nil
+}
+
+// stripFirstDir removes the top level directory ("go/" in Go release
+// archives) from entry name. ok is false for the top level directory itself.
+func stripFirstDir(name string) (stripped string, ok bool) {
+	name = strings.TrimPrefix(strings.Replace(name, "\\", "/", -1), "./")
+	i := strings.Index(name, "/")
+	if i < 0 || i == len(name)-1 {
+		return "", false
+	}
+	return name[i+1:], true
+}
+
+// extractTar extracts tar stream into targetPath. If stripDir is true, the
+// top level directory of entries is removed. Entries escaping targetPath,
+// directly or through symbolic links created by the archive, are rejected.
+func extractTar(r io.Reader, targetPath string, stripDir bool, progCback func(format string, arg ...interface{})) error {
+	tarFile := tar.NewReader(r)
+	links := make([]string, 0)
+	dirs := make([]*tar.Header, 0)
+	for {
+		f, err := tarFile.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "Error opening tar file")
+		}
+		name := f.Name
+		if stripDir {
+			var ok bool
+			if name, ok = stripFirstDir(name); !ok {
+				continue
+			}
+		}
+		dstPath, err := safeJoin(targetPath, name)
+		if err != nil {
+			return err
+		}
+		for _, l := range links {
+			if strings.HasPrefix(dstPath, l+string(filepath.Separator)) {
+				return fmt.Errorf("Illegal path in archive through symbolic link: %s", f.Name)
+			}
+		}
+
+		switch f.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(dstPath, 0755)
+			if err != nil {
+				return errors.Wrap(err, "Cannot create directory")
+			}
+			f.Name = dstPath
+			dirs = append(dirs, f)
+			continue
+		case tar.TypeSymlink:
+			progCback("Extracting %s...\n", dstPath)
+			if err = os.MkdirAll(filepath.Dir(dstPath), 0755); err == nil {
+				os.Remove(dstPath)
+				err = os.Symlink(f.Linkname, dstPath)
+			}
+			if err != nil {
+				return errors.Wrap(err, "Cannot create link")
+			}
+			links = append(links, dstPath)
+			continue
+		case tar.TypeLink:
+			linkName := f.Linkname
+			if stripDir {
+				linkName, _ = stripFirstDir(linkName)
+			}
+			oldPath, err := safeJoin(targetPath, linkName)
+			if err != nil {
+				return err
+			}
+			os.Remove(dstPath)
+			if err = os.Link(oldPath, dstPath); err != nil {
+				return errors.Wrap(err, "Cannot create link")
+			}
+			continue
+		case tar.TypeReg, tar.TypeRegA:
+		default:
+			progCback("Skipping %s of unsupported type\n", dstPath)
+			continue
+		}
+
+		progCback("Extracting %s...\n", dstPath)
+		err = os.MkdirAll(filepath.Dir(dstPath), 0755)
+		if err != nil {
+			return errors.Wrap(err, "Cannot create directory")
+		}
+		os.Remove(dstPath)
+		dstFile, err := os.OpenFile(dstPath, os.O_CREATE|os.O_WRONLY|os.O_EXCL, f.FileInfo().Mode().Perm())
+		if err != nil {
+			return errors.Wrap(err, "Cannot create file")
+		}
+		_, err = io.Copy(dstFile, tarFile)
+		if closeErr := dstFile.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return errors.Wrap(err, "Cannot write data")
+		}
+		os.Chmod(dstPath, f.FileInfo().Mode().Perm())
+		os.Chtimes(dstPath, f.ModTime, f.ModTime)
+	}
+
+	// Apply directory attributes after their content is extracted
+	for i := len(dirs) - 1; i >= 0; i-- {
+		os.Chmod(dirs[i].Name, dirs[i].FileInfo().Mode().Perm())
+		os.Chtimes(dirs[i].Name, dirs[i].ModTime, dirs[i].ModTime)
+	}
+	return nil
+}
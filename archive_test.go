@@ -0,0 +1,234 @@
+package goup
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type testEntry struct {
+	name     string
+	typeflag byte
+	content  string
+	linkname string
+}
+
+func makeTar(t *testing.T, entries []testEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Typeflag: e.typeflag, Mode: 0644, Size: int64(len(e.content)), Linkname: e.linkname}
+		if e.typeflag == tar.TypeDir {
+			hdr.Mode = 0755
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(e.content))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtractTar(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []testEntry
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			"TestCase 1",
+			[]testEntry{
+				{"go/", tar.TypeDir, "", ""},
+				{"go/bin/", tar.TypeDir, "", ""},
+				{"go/bin/go", tar.TypeReg, "go binary", ""},
+				{"go/VERSION", tar.TypeReg, "go1.21.1", ""},
+			},
+			map[string]string{"bin/go": "go binary", "VERSION": "go1.21.1"},
+			false,
+		}, {
+			"TestCase 2",
+			[]testEntry{{"go/../../evil", tar.TypeReg, "evil", ""}},
+			nil,
+			true,
+		}, {
+			"TestCase 3",
+			[]testEntry{{"go/bin/../../../evil", tar.TypeReg, "evil", ""}},
+			nil,
+			true,
+		}, {
+			"TestCase 4",
+			[]testEntry{
+				{"go/link", tar.TypeSymlink, "", os.TempDir()},
+				{"go/link/evil", tar.TypeReg, "evil", ""},
+			},
+			nil,
+			true,
+		}, {
+			"TestCase 5",
+			[]testEntry{
+				{"go/VERSION", tar.TypeReg, "go1.21.1", ""},
+				{"go/VERSION.link", tar.TypeLink, "", "go/VERSION"},
+			},
+			map[string]string{"VERSION": "go1.21.1", "VERSION.link": "go1.21.1"},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "goup-extract")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			target := filepath.Join(dir, "a", "b")
+			err = extractTar(bytes.NewReader(makeTar(t, tt.entries)), target, true, func(string, ...interface{}) {})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("extractTar() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "evil")); err == nil {
+				t.Error("File written outside target")
+			}
+			for name, content := range tt.want {
+				if data, err := ioutil.ReadFile(filepath.Join(target, filepath.FromSlash(name))); err != nil || string(data) != content {
+					t.Errorf("%s = %q, %v; want %q", name, data, err, content)
+				}
+			}
+		})
+	}
+}
+
+func makeZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func makeTarGz(t *testing.T, entries []testEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	gzw.Write(makeTar(t, entries))
+	if err := gzw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtractorFor(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		want    Extractor
+		wantErr bool
+	}{
+		{"TestCase 1", "go1.21.1.linux-amd64.tar.gz", TarGzExtractor{}, false},
+		{"TestCase 2", "go1.21.1.windows-amd64.zip", ZipExtractor{}, false},
+		{"TestCase 3", "GO1.21.1.WINDOWS-AMD64.ZIP", ZipExtractor{}, false},
+		{"TestCase 4", "go1.21.1.darwin-amd64.pkg", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractorFor(tt.file)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ExtractorFor() = %T, %v; want %T", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractArchive(t *testing.T) {
+	tests := []struct {
+		name    string
+		archive []byte
+		size    int64
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			"TestCase 1",
+			makeTarGz(t, []testEntry{
+				{"go/bin/go", tar.TypeReg, "go binary", ""},
+				{"go/VERSION", tar.TypeReg, "go1.21.1", ""},
+			}),
+			-1,
+			map[string]string{"bin/go": "go binary", "VERSION": "go1.21.1"},
+			false,
+		}, {
+			"TestCase 2",
+			makeZip(t, map[string]string{"go/bin/go.exe": "go binary", "go/VERSION": "go1.21.1"}),
+			-1,
+			map[string]string{"bin/go.exe": "go binary", "VERSION": "go1.21.1"},
+			false,
+		}, {
+			"TestCase 3",
+			makeZip(t, map[string]string{"go/../../evil": "evil"}),
+			-1,
+			nil,
+			true,
+		}, {
+			"TestCase 4",
+			[]byte("not an archive"),
+			-1,
+			nil,
+			true,
+		}, {
+			"TestCase 5",
+			makeZip(t, map[string]string{"go/VERSION": "go1.21.1"}),
+			1,
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "goup-extract")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			src := filepath.Join(dir, "archive")
+			if err := ioutil.WriteFile(src, tt.archive, 0644); err != nil {
+				t.Fatal(err)
+			}
+			f, err := os.Open(src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			target := filepath.Join(dir, "a", "b")
+			err = ExtractArchive(f, tt.size, target, func(string, ...interface{}) {})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExtractArchive() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "evil")); err == nil {
+				t.Error("File written outside target")
+			}
+			for name, content := range tt.want {
+				if data, err := ioutil.ReadFile(filepath.Join(target, filepath.FromSlash(name))); err != nil || string(data) != content {
+					t.Errorf("%s = %q, %v; want %q", name, data, err, content)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package goup
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/karrick/godirwalk"
+	"github.com/pkg/errors"
+)
+
+const (
+	// BackupArchiveExt is the extension of compressed backups
+	BackupArchiveExt = ".tar.gz"
+	// ManifestExt is appended to backup archive name for its manifest
+	ManifestExt = ".sha256"
+)
+
+// IsBackupArchive tells if backup path is a compressed backup
+func IsBackupArchive(path string) bool {
+	return strings.HasSuffix(path, BackupArchiveExt)
+}
+
+// CreateBackupArchive writes directory src as a gzipped tar to archivePath,
+// streaming directly from the directory walk. A manifest holding SHA-256 of
+// every regular file (in sha256sum format) is written next to the archive
+// with ManifestExt appended.
+func CreateBackupArchive(src, archivePath string) (err error) {
+	f, err := os.Create(archivePath)
+	if err != nil {
+		return errors.Wrap(err, "Cannot create backup archive")
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	gzw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gzw)
+	manifest := make(map[string]string)
+
+	buf := make([]byte, BufSize)
+	err = godirwalk.Walk(src, &godirwalk.Options{
+		ScratchBuffer: buf,
+		Callback: func(osPathname string, de *godirwalk.Dirent) error {
+			rel, err := filepath.Rel(src, osPathname)
+			if err != nil || rel == "." {
+				return err
+			}
+			fi, err := os.Lstat(osPathname)
+			if err != nil {
+				return err
+			}
+			link := ""
+			if fi.Mode()&os.ModeSymlink != 0 {
+				if link, err = os.Readlink(osPathname); err != nil {
+					return err
+				}
+			}
+			hdr, err := tar.FileInfoHeader(fi, link)
+			if err != nil {
+				return err
+			}
+			hdr.Name = filepath.ToSlash(rel)
+			if fi.IsDir() {
+				hdr.Name += "/"
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				return err
+			}
+			if !fi.Mode().IsRegular() {
+				return nil
+			}
+			sum, err := copyHashed(tw, osPathname)
+			if err != nil {
+				return err
+			}
+			manifest[hdr.Name] = sum
+			return nil
+		},
+	})
+	if err == nil {
+		err = tw.Close()
+	}
+	if err == nil {
+		err = gzw.Close()
+	}
+	if err != nil {
+		return errors.Wrap(err, "Cannot write backup archive")
+	}
+	return writeManifest(archivePath+ManifestExt, manifest)
+}
+
+// copyHashed copies file content to w and returns its SHA-256
+func copyHashed(w io.Writer, path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(w, h), f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func writeManifest(path string, manifest map[string]string) error {
+	names := make([]string, 0, len(manifest))
+	for name := range manifest {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrap(err, "Cannot create backup manifest")
+	}
+	w := bufio.NewWriter(f)
+	for _, name := range names {
+		fmt.Fprintf(w, "%s  %s\n", manifest[name], name)
+	}
+	err = w.Flush()
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func readManifest(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot open backup manifest")
+	}
+	defer f.Close()
+	manifest := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "  ", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("Malformed manifest line: %s", scanner.Text())
+		}
+		manifest[parts[1]] = parts[0]
+	}
+	return manifest, scanner.Err()
+}
+
+// VerifyBackupArchive checks every file in backup archive against its
+// manifest
+func VerifyBackupArchive(archivePath string) error {
+	manifest, err := readManifest(archivePath + ManifestExt)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gzr, err := gzip.NewReader(f)
+	if err != nil {
+		return errors.Wrap(err, "Error opening GZip archive")
+	}
+	tr := tar.NewReader(gzr)
+	seen := 0
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return errors.Wrap(err, "Backup archive is corrupted")
+		}
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA {
+			continue
+		}
+		want, ok := manifest[hdr.Name]
+		if !ok {
+			return fmt.Errorf("%s is not in backup manifest", hdr.Name)
+		}
+		h := sha256.New()
+		if _, err := io.Copy(h, tr); err != nil {
+			return errors.Wrap(err, "Backup archive is corrupted")
+		}
+		if got := hex.EncodeToString(h.Sum(nil)); got != want {
+			return fmt.Errorf("Checksum mismatch for %s in backup", hdr.Name)
+		}
+		seen++
+	}
+	if seen != len(manifest) {
+		return fmt.Errorf("Backup archive has %d files, manifest lists %d", seen, len(manifest))
+	}
+	return nil
+}
+
+// RestoreBackupArchive verifies backup archive and extracts it to goPath,
+// replacing its content
+func RestoreBackupArchive(archivePath, goPath string) error {
+	if err := VerifyBackupArchive(archivePath); err != nil {
+		return err
+	}
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gzr, err := gzip.NewReader(f)
+	if err != nil {
+		return errors.Wrap(err, "Error opening GZip archive")
+	}
+	if err := os.RemoveAll(goPath); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(goPath, 0755); err != nil {
+		return err
+	}
+	return extractTar(gzr, goPath, false, func(string, ...interface{}) {})
+}
+
+// RemoveBackup deletes a backup directory or archive with its manifest
+func RemoveBackup(backupPath string) error {
+	if IsBackupArchive(backupPath) {
+		if err := os.Remove(backupPath + ManifestExt); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.RemoveAll(backupPath)
+}
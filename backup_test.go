@@ -0,0 +1,116 @@
+package goup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestBackupArchive(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Symbolic links and permission bits are not fully supported on Windows")
+	}
+	dir, err := ioutil.TempDir("", "goup-backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		os.Chmod(filepath.Join(dir, "src", "ReadOnlyDir"), 0755)
+		os.Chmod(filepath.Join(dir, "restored", "ReadOnlyDir"), 0755)
+		os.RemoveAll(dir)
+	}()
+	src := filepath.Join(dir, "src")
+	restored := filepath.Join(dir, "restored")
+	archive := filepath.Join(dir, "backup"+BackupArchiveExt)
+	mtime := prepareTestTree(t, src)
+
+	if err := CreateBackupArchive(src, archive); err != nil {
+		t.Fatalf("CreateBackupArchive() error = %v", err)
+	}
+	if err := VerifyBackupArchive(archive); err != nil {
+		t.Fatalf("VerifyBackupArchive() error = %v", err)
+	}
+	// Existing content is replaced
+	os.MkdirAll(restored, 0755)
+	ioutil.WriteFile(filepath.Join(restored, "Leftover"), nil, 0644)
+	if err := RestoreBackup(archive, restored); err != nil {
+		t.Fatalf("RestoreBackup() error = %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(restored, "Leftover")); !os.IsNotExist(err) {
+		t.Error("Leftover not removed by restore")
+	}
+	for _, f := range []string{"TestFile1", "A/B/C/TestFile2", "A/Exec", "A/ReadOnly", "ReadOnlyDir/TestFile3"} {
+		srcInfo, _ := os.Stat(filepath.Join(src, filepath.FromSlash(f)))
+		info, err := os.Stat(filepath.Join(restored, filepath.FromSlash(f)))
+		if err != nil {
+			t.Errorf("%s not restored", f)
+			continue
+		}
+		if info.Mode() != srcInfo.Mode() || !info.ModTime().Equal(mtime) {
+			t.Errorf("%s restored with mode %v mtime %v", f, info.Mode(), info.ModTime())
+		}
+		if data, _ := ioutil.ReadFile(filepath.Join(restored, filepath.FromSlash(f))); string(data) != "Hello "+f {
+			t.Errorf("%s restored with content %q", f, data)
+		}
+	}
+	if target, _ := os.Readlink(filepath.Join(restored, "A", "Link")); target != filepath.FromSlash("B/C/TestFile2") {
+		t.Errorf("A/Link restored pointing to %q", target)
+	}
+	if info, err := os.Stat(filepath.Join(restored, "Empty", "Nested")); err != nil || !info.IsDir() {
+		t.Error("Empty/Nested not restored")
+	}
+
+	if err := RemoveBackup(archive); err != nil {
+		t.Fatalf("RemoveBackup() error = %v", err)
+	}
+	if _, err := os.Stat(archive + ManifestExt); !os.IsNotExist(err) {
+		t.Error("Manifest not removed")
+	}
+}
+
+func TestBackupArchive_Corrupted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goup-backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "src")
+	os.MkdirAll(filepath.Join(src, "bin"), 0755)
+	ioutil.WriteFile(filepath.Join(src, "bin", "go"), []byte("go binary"), 0755)
+	ioutil.WriteFile(filepath.Join(src, "VERSION"), []byte("go1.21.1"), 0644)
+	archive := filepath.Join(dir, "backup"+BackupArchiveExt)
+	if err := CreateBackupArchive(src, archive); err != nil {
+		t.Fatalf("CreateBackupArchive() error = %v", err)
+	}
+
+	manifest, _ := ioutil.ReadFile(archive + ManifestExt)
+	tests := []struct {
+		name     string
+		manifest string
+	}{
+		{"TestCase 1", strings.Replace(string(manifest), "  VERSION", "x  VERSION", 1)},
+		{"TestCase 2", strings.SplitN(string(manifest), "\n", 2)[1]},
+		{"TestCase 3", string(manifest) + strings.Repeat("0", 64) + "  bin/gofmt\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ioutil.WriteFile(archive+ManifestExt, []byte(tt.manifest), 0644)
+			if err := VerifyBackupArchive(archive); err == nil {
+				t.Error("VerifyBackupArchive() accepts corrupted backup")
+			}
+			target := filepath.Join(dir, "target")
+			os.MkdirAll(target, 0755)
+			ioutil.WriteFile(filepath.Join(target, "VERSION"), []byte("go1.21.2"), 0644)
+			if err := RestoreBackup(archive, target); err == nil {
+				t.Error("RestoreBackup() restores corrupted backup")
+			}
+			if data, _ := ioutil.ReadFile(filepath.Join(target, "VERSION")); string(data) != "go1.21.2" {
+				t.Error("Target modified by failed restore")
+			}
+		})
+	}
+}
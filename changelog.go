@@ -0,0 +1,195 @@
+package goup
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultNotesSource is the site hosting Go release history and notes
+	DefaultNotesSource = "https://go.dev"
+	releaseHistoryPath = "/doc/devel/release"
+)
+
+var releasedRegexp = regexp.MustCompile(`^go[0-9.a-z]+ \(released ([0-9-]+)\)\s*`)
+
+// ReleaseNote summarises a Go release
+type ReleaseNote struct {
+	Version VersionInfo
+	Date    string
+	Summary string
+	// URL points to the release notes of a minor release or the milestone of
+	// a point release
+	URL string
+	// Highlights are the section titles of minor release notes
+	Highlights []string
+}
+
+// ReleaseNotes gathers the notes of releases newer than from and up to to,
+// oldest first. source is the base URL of go.dev or a local directory holding
+// a copy of its doc pages (doc/devel/release and doc/go1.N, optionally with
+// .html extension).
+func ReleaseNotes(source string, from, to VersionInfo) ([]ReleaseNote, error) {
+	if source == "" {
+		source = DefaultNotesSource
+	}
+	doc, err := openDoc(source, releaseHistoryPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot read release history")
+	}
+	notes := make([]ReleaseNote, 0)
+	doc.Find("h2[id^='go'], p[id^='go']").Each(func(i int, s *goquery.Selection) {
+		id, _ := s.Attr("id")
+		ver, err := ExtractVersionInfo(id[2:])
+		if err != nil || CompareVersion(ver, from) <= 0 || CompareVersion(ver, to) > 0 {
+			return
+		}
+		note := ReleaseNote{Version: ver}
+		text := s.Text()
+		if s.Is("h2") {
+			text = s.NextFilteredUntil("p", "h2").First().Text()
+			note.URL = resolveURL(source, fmt.Sprintf("/doc/go%d.%d", ver.Major, ver.Minor))
+		} else {
+			href, _ := s.Find("a[href*='milestone']").Attr("href")
+			note.URL = resolveURL(source, href)
+		}
+		if m := releasedRegexp.FindStringSubmatch(strings.TrimSpace(s.Text())); m != nil {
+			note.Date = m[1]
+		}
+		note.Summary = collapseSpace(releasedRegexp.ReplaceAllString(strings.TrimSpace(text), ""))
+		if note.Summary != "" {
+			note.Summary = strings.ToUpper(note.Summary[:1]) + note.Summary[1:]
+		}
+		notes = append(notes, note)
+	})
+	sort.Slice(notes, func(i, j int) bool {
+		return CompareVersion(notes[i].Version, notes[j].Version) < 0
+	})
+
+	// Release notes of minor release may not be available in local copy,
+	// highlights are left empty in that case
+	for i := range notes {
+		if notes[i].Version.Build == 0 {
+			notes[i].Highlights, _ = minorHighlights(source, notes[i].Version)
+		}
+	}
+	return notes, nil
+}
+
+// minorHighlights returns the top level section titles of minor release notes
+func minorHighlights(source string, ver VersionInfo) ([]string, error) {
+	doc, err := openDoc(source, fmt.Sprintf("/doc/go%d.%d", ver.Major, ver.Minor))
+	if err != nil {
+		return nil, err
+	}
+	highlights := make([]string, 0)
+	doc.Find("h2").Each(func(i int, s *goquery.Selection) {
+		title := collapseSpace(s.Text())
+		if title != "" && title != "Introduction to Go "+strconv.Itoa(ver.Major)+"."+strconv.Itoa(ver.Minor) {
+			highlights = append(highlights, title)
+		}
+	})
+	return highlights, nil
+}
+
+// openDoc loads a go.dev page from website or local copy
+func openDoc(source, docPath string) (*goquery.Document, error) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		resp, err := httpClient.Get(strings.TrimSuffix(source, "/") + docPath)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != 200 {
+			return nil, errors.New("Error code: " + strconv.Itoa(resp.StatusCode))
+		}
+		return goquery.NewDocumentFromReader(resp.Body)
+	}
+	localPath := filepath.Join(source, filepath.FromSlash(docPath))
+	f, err := os.Open(localPath)
+	if os.IsNotExist(err) {
+		f, err = os.Open(localPath + ".html")
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return goquery.NewDocumentFromReader(f)
+}
+
+func resolveURL(source, href string) string {
+	if href == "" || strings.Contains(href, "://") {
+		return href
+	}
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		source = DefaultNotesSource
+	}
+	return strings.TrimSuffix(source, "/") + href
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// RenderReleaseNotes writes notes as terminal text, or markdown if markdown
+// is true
+func RenderReleaseNotes(w io.Writer, notes []ReleaseNote, markdown bool) error {
+	var sb strings.Builder
+	for _, n := range notes {
+		title := "Go " + n.Version.String()
+		if n.Date != "" {
+			title += " (released " + n.Date + ")"
+		}
+		if markdown {
+			fmt.Fprintf(&sb, "## %s\n\n%s\n\n", title, n.Summary)
+			for _, h := range n.Highlights {
+				fmt.Fprintf(&sb, "- %s\n", h)
+			}
+			if len(n.Highlights) > 0 {
+				sb.WriteString("\n")
+			}
+			if n.URL != "" {
+				fmt.Fprintf(&sb, "<%s>\n\n", n.URL)
+			}
+			continue
+		}
+		fmt.Fprintf(&sb, "%s\n%s\n%s\n", title, strings.Repeat("=", len(title)), wrapText(n.Summary, 78))
+		for _, h := range n.Highlights {
+			fmt.Fprintf(&sb, "  * %s\n", h)
+		}
+		if n.URL != "" {
+			fmt.Fprintf(&sb, "  %s\n", n.URL)
+		}
+		sb.WriteString("\n")
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// wrapText breaks text into lines no longer than width
+func wrapText(text string, width int) string {
+	var sb strings.Builder
+	lineLen := 0
+	for _, word := range strings.Fields(text) {
+		if lineLen > 0 && lineLen+1+len(word) > width {
+			sb.WriteString("\n")
+			lineLen = 0
+		}
+		if lineLen > 0 {
+			sb.WriteString(" ")
+			lineLen++
+		}
+		sb.WriteString(word)
+		lineLen += len(word)
+	}
+	return sb.String()
+}
@@ -0,0 +1,86 @@
+package goup
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testNotesSource = "testdata/godev"
+
+func TestReleaseNotes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, testNotesSource+r.URL.Path+".html")
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		source string
+		from   string
+		to     string
+		want   []string
+	}{
+		{"TestCase 1", testNotesSource, "1.21.1", "1.22.2", []string{"1.22", "1.22.1", "1.22.2"}},
+		{"TestCase 2", testNotesSource, "1.20", "1.21.1", []string{"1.21", "1.21.1"}},
+		{"TestCase 3", testNotesSource, "1.22.1", "1.22.2", []string{"1.22.2"}},
+		{"TestCase 4", testNotesSource, "1.22.2", "1.22.2", []string{}},
+		{"TestCase 5", srv.URL, "1.21", "1.22", []string{"1.21.1", "1.22"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notes, err := ReleaseNotes(tt.source, mustVersion(t, tt.from), mustVersion(t, tt.to))
+			if err != nil {
+				t.Fatalf("ReleaseNotes() error = %v", err)
+			}
+			got := make([]string, 0)
+			for _, n := range notes {
+				got = append(got, n.Version.String())
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("ReleaseNotes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReleaseNotes_Content(t *testing.T) {
+	notes, err := ReleaseNotes(testNotesSource, mustVersion(t, "1.21.1"), mustVersion(t, "1.22.1"))
+	if err != nil {
+		t.Fatalf("ReleaseNotes() error = %v", err)
+	}
+	if len(notes) != 2 {
+		t.Fatalf("ReleaseNotes() returns %d notes, want 2", len(notes))
+	}
+	minor, point := notes[0], notes[1]
+	if minor.Date != "2024-02-06" || minor.URL != "https://go.dev/doc/go1.22" {
+		t.Errorf("Unexpected minor release note %+v", minor)
+	}
+	if strings.Join(minor.Highlights, "|") != "Changes to the language|Tools|Runtime|Standard library" {
+		t.Errorf("Unexpected highlights %v", minor.Highlights)
+	}
+	if point.Date != "2024-03-05" || !strings.HasPrefix(point.Summary, "Includes security fixes to the crypto/x509,") {
+		t.Errorf("Unexpected point release note %+v", point)
+	}
+	if !strings.Contains(point.URL, "milestone%3AGo1.22.1") {
+		t.Errorf("Unexpected milestone URL %s", point.URL)
+	}
+
+	var text, md bytes.Buffer
+	if err := RenderReleaseNotes(&text, notes, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := RenderReleaseNotes(&md, notes, true); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(text.String(), "\n") {
+		if len(line) > 78 && !strings.HasPrefix(line, "  http") {
+			t.Errorf("Text line too long: %q", line)
+		}
+	}
+	if !strings.Contains(md.String(), "## Go 1.22.1 (released 2024-03-05)") || !strings.Contains(md.String(), "- Runtime\n") {
+		t.Errorf("Unexpected markdown output:\n%s", md.String())
+	}
+}
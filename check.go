@@ -0,0 +1,172 @@
+package goup
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// CheckResult is the outcome of a background version check, cached in
+// CheckCacheFile
+type CheckResult struct {
+	CheckedAt       time.Time
+	GoRoot          string
+	Local           VersionInfo
+	Latest          VersionInfo
+	Policy          string
+	UpdateAvailable bool
+	Error           string `json:",omitempty"`
+	// Notify lists how user asked to be notified, e.g. stderr
+	Notify []string `json:",omitempty"`
+	// BannerShown is set once user is told about Latest on stderr
+	BannerShown bool
+}
+
+// CheckCacheFile is where the last CheckResult is kept
+func CheckCacheFile() string {
+	return filepath.Join(HomeDir(), "check.json")
+}
+
+// LoadCheckResult reads the cached CheckResult
+func LoadCheckResult() (CheckResult, error) {
+	var r CheckResult
+	data, err := ioutil.ReadFile(CheckCacheFile())
+	if err != nil {
+		return r, err
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return r, errors.Wrap(err, "Cannot parse check cache")
+	}
+	return r, nil
+}
+
+// SaveCheckResult writes r to the cache, replacing it atomically
+func SaveCheckResult(r CheckResult) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(CheckCacheFile(), data); err != nil {
+		return errors.Wrap(err, "Cannot write check cache")
+	}
+	return nil
+}
+
+// Message describes the result for notifications
+func (r CheckResult) Message() string {
+	switch {
+	case r.Error != "":
+		return "Checking Go version failed: " + r.Error
+	case r.UpdateAvailable:
+		return fmt.Sprintf("Go %v is available, %s has Go %v. Run goup upgrade to upgrade.", r.Latest, r.GoRoot, r.Local)
+	}
+	return fmt.Sprintf("Go %v in %s is up to date", r.Local, r.GoRoot)
+}
+
+// IsNewUpdate tells if r finds an update not found by previous check, so
+// user is notified once per release
+func (r CheckResult) IsNewUpdate(previous CheckResult) bool {
+	return r.UpdateAvailable && (!previous.UpdateAvailable || previous.Latest != r.Latest || previous.GoRoot != r.GoRoot)
+}
+
+// webhookPayload is posted by PostWebhook. Text makes it usable as a Slack
+// or Mattermost incoming webhook as is.
+type webhookPayload struct {
+	Text            string    `json:"text"`
+	Host            string    `json:"host"`
+	GoRoot          string    `json:"goroot"`
+	Local           string    `json:"local_version"`
+	Latest          string    `json:"latest_version"`
+	UpdateAvailable bool      `json:"update_available"`
+	CheckedAt       time.Time `json:"checked_at"`
+}
+
+// PostWebhook posts r as JSON to url
+func PostWebhook(url string, r CheckResult) error {
+	host, _ := os.Hostname()
+	data, err := json.Marshal(webhookPayload{
+		Text:            r.Message(),
+		Host:            host,
+		GoRoot:          r.GoRoot,
+		Local:           r.Local.String(),
+		Latest:          r.Latest.String(),
+		UpdateAvailable: r.UpdateAvailable,
+		CheckedAt:       r.CheckedAt,
+	})
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrap(err, "Cannot post webhook")
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.New("Webhook error code: " + strconv.Itoa(resp.StatusCode))
+	}
+	return nil
+}
+
+// DesktopNotifyCommand returns the command showing a desktop notification
+// on this platform
+func DesktopNotifyCommand(title, message string) ([]string, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{"osascript", "-e", fmt.Sprintf("display notification %s with title %s", appleScriptString(message), appleScriptString(title))}, nil
+	case "windows":
+		return nil, errors.New("Desktop notification is not supported on Windows, use --notify-command")
+	}
+	return []string{"notify-send", "--app-name=goup", title, message}, nil
+}
+
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// Schedules lists how often a scheduled check can run
+var Schedules = []string{"hourly", "daily", "weekly"}
+
+// SystemdUnits returns service and timer units running exe with args on
+// schedule, to be installed as systemd user units
+func SystemdUnits(exe string, args []string, schedule string) (service, timer string) {
+	cmdline := make([]string, 0, len(args)+1)
+	for _, a := range append([]string{exe}, args...) {
+		cmdline = append(cmdline, systemdQuote(a))
+	}
+	service = "[Unit]\nDescription=Check for Go updates with goup\n\n" +
+		"[Service]\nType=oneshot\nExecStart=" + strings.Join(cmdline, " ") + "\n"
+	timer = "[Unit]\nDescription=Check for Go updates with goup " + schedule + "\n\n" +
+		"[Timer]\nOnCalendar=" + schedule + "\nPersistent=true\nRandomizedDelaySec=1h\n\n" +
+		"[Install]\nWantedBy=timers.target\n"
+	return service, timer
+}
+
+func systemdQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\"'\\%$") {
+		return s
+	}
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "%", "%%", "$", "$$").Replace(s)
+	return `"` + s + `"`
+}
+
+// CronEntry returns crontab line running exe with args on schedule
+func CronEntry(exe string, args []string, schedule string) string {
+	spec := map[string]string{"hourly": "17 * * * *", "daily": "17 9 * * *", "weekly": "17 9 * * 1"}[schedule]
+	cmdline := make([]string, 0, len(args)+1)
+	for _, a := range append([]string{exe}, args...) {
+		// % is newline in crontab
+		cmdline = append(cmdline, strings.Replace(quotePOSIX(a), "%", `\%`, -1))
+	}
+	return spec + " " + strings.Join(cmdline, " ")
+}
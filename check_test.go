@@ -0,0 +1,146 @@
+package goup
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckResultCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goup-check")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	setGoupHome(t, dir)
+
+	if _, err := LoadCheckResult(); !os.IsNotExist(err) {
+		t.Errorf("LoadCheckResult() without cache error = %v", err)
+	}
+	r := CheckResult{
+		CheckedAt:       time.Date(2024, 2, 6, 9, 17, 0, 0, time.UTC),
+		GoRoot:          "/usr/local/go",
+		Local:           mustVersion(t, "1.21.5"),
+		Latest:          mustVersion(t, "1.21.7"),
+		Policy:          "patch",
+		UpdateAvailable: true,
+		Notify:          []string{"stderr"},
+	}
+	if err := SaveCheckResult(r); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadCheckResult()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.CheckedAt.Equal(r.CheckedAt) || got.Latest != r.Latest || got.Local != r.Local || !got.UpdateAvailable || len(got.Notify) != 1 {
+		t.Errorf("LoadCheckResult() = %+v, want %+v", got, r)
+	}
+	entries, _ := ioutil.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Temporary file is left in %s", dir)
+	}
+}
+
+func TestIsNewUpdate(t *testing.T) {
+	v1215, v1217, v1218 := mustVersion(t, "1.21.5"), mustVersion(t, "1.21.7"), mustVersion(t, "1.21.8")
+	tests := []struct {
+		name     string
+		current  CheckResult
+		previous CheckResult
+		want     bool
+	}{
+		{"TestCase 1", CheckResult{Local: v1215, Latest: v1217, UpdateAvailable: true}, CheckResult{}, true},
+		{"TestCase 2", CheckResult{Local: v1215, Latest: v1217, UpdateAvailable: true}, CheckResult{Local: v1215, Latest: v1217, UpdateAvailable: true}, false},
+		{"TestCase 3", CheckResult{Local: v1215, Latest: v1218, UpdateAvailable: true}, CheckResult{Local: v1215, Latest: v1217, UpdateAvailable: true}, true},
+		{"TestCase 4", CheckResult{Local: v1215, Latest: v1217, UpdateAvailable: true}, CheckResult{Local: v1215, Latest: v1215}, true},
+		{"TestCase 5", CheckResult{Local: v1217, Latest: v1217}, CheckResult{Local: v1215, Latest: v1217, UpdateAvailable: true}, false},
+		{"TestCase 6", CheckResult{GoRoot: "/opt/go", Local: v1215, Latest: v1217, UpdateAvailable: true}, CheckResult{GoRoot: "/usr/local/go", Local: v1215, Latest: v1217, UpdateAvailable: true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.current.IsNewUpdate(tt.previous); got != tt.want {
+				t.Errorf("IsNewUpdate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostWebhook(t *testing.T) {
+	var payload map[string]interface{}
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		contentType = r.Header.Get("Content-Type")
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	r := CheckResult{
+		GoRoot:          "/usr/local/go",
+		Local:           mustVersion(t, "1.21.5"),
+		Latest:          mustVersion(t, "1.21.7"),
+		UpdateAvailable: true,
+	}
+	if err := PostWebhook(server.URL+"/hook", r); err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "application/json" {
+		t.Errorf("Content-Type = %q", contentType)
+	}
+	want := map[string]interface{}{
+		"goroot":           "/usr/local/go",
+		"local_version":    "1.21.5",
+		"latest_version":   "1.21.7",
+		"update_available": true,
+		"text":             r.Message(),
+	}
+	for k, v := range want {
+		if payload[k] != v {
+			t.Errorf("Payload %s = %v, want %v", k, payload[k], v)
+		}
+	}
+	if err := PostWebhook(server.URL+"/fail", r); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("PostWebhook() to failing server error = %v", err)
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	service, timer := SystemdUnits("/home/a b/goup", []string{"check", "--webhook", "https://h/x?a=100%"}, "daily")
+	if want := `ExecStart="/home/a b/goup" check --webhook "https://h/x?a=100%%"`; !strings.Contains(service, want+"\n") {
+		t.Errorf("SystemdUnits() service = %q, want %q", service, want)
+	}
+	if !strings.Contains(timer, "OnCalendar=daily\n") || !strings.Contains(timer, "WantedBy=timers.target") {
+		t.Errorf("SystemdUnits() timer = %q", timer)
+	}
+
+	tests := []struct {
+		name     string
+		schedule string
+		args     []string
+		want     string
+	}{
+		{"TestCase 1", "hourly", []string{"check"}, `17 * * * * '/usr/bin/goup' 'check'`},
+		{"TestCase 2", "daily", []string{"check", "--notify", "desktop"}, `17 9 * * * '/usr/bin/goup' 'check' '--notify' 'desktop'`},
+		{"TestCase 3", "weekly", []string{"check", "--webhook", "https://h/x?a=100%"}, `17 9 * * 1 '/usr/bin/goup' 'check' '--webhook' 'https://h/x?a=100\%'`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CronEntry("/usr/bin/goup", tt.args, tt.schedule); got != tt.want {
+				t.Errorf("CronEntry() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
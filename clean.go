@@ -0,0 +1,200 @@
+package goup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// StaleTempAge is how old temporary files, partial downloads and staging
+// directories must be before they are considered left over, so files of a
+// running goup are not touched
+const StaleTempAge = 24 * time.Hour
+
+// CleanKind tells what a CleanItem is
+type CleanKind string
+
+const (
+	// CleanBackup is a backup of Go installation not needed by any pending
+	// upgrade
+	CleanBackup CleanKind = "backup"
+	// CleanDownload is a release archive kept in DownloadsDir
+	CleanDownload CleanKind = "download"
+	// CleanPartial is an interrupted download
+	CleanPartial CleanKind = "partial download"
+	// CleanStaging is a staging directory left by an interrupted install
+	CleanStaging CleanKind = "staging"
+	// CleanTemp is a temporary file or directory of goup
+	CleanTemp CleanKind = "temporary"
+)
+
+// CleanItem is a file or directory left over by goup
+type CleanItem struct {
+	Path    string
+	Kind    CleanKind
+	Size    int64
+	ModTime time.Time
+}
+
+// CleanOptions selects what FindLeftovers looks for
+type CleanOptions struct {
+	// TempDir is where backups and temporary files are, os.TempDir() if empty
+	TempDir string
+	// Roots are Go installations whose staging directories are looked for,
+	// in addition to those in VersionsDir
+	Roots []string
+	// BackupAge is the minimum age of backups to remove
+	BackupAge time.Duration
+}
+
+// FindLeftovers lists backups, downloads, staging directories and temporary
+// files of goup which can be removed, oldest first. Backups of pending
+// upgrades are never listed.
+func FindLeftovers(opts CleanOptions) ([]CleanItem, error) {
+	if opts.TempDir == "" {
+		opts.TempDir = os.TempDir()
+	}
+	journals, err := PendingJournals()
+	if err != nil {
+		return nil, err
+	}
+	inUse := make(map[string]bool)
+	for _, j := range journals {
+		inUse[filepath.Clean(j.BackupPath)] = true
+	}
+
+	now := time.Now()
+	items := make([]CleanItem, 0)
+	add := func(path string, fi os.FileInfo, kind CleanKind) {
+		size := fi.Size()
+		if fi.IsDir() {
+			size, _ = DirSize(path)
+		}
+		items = append(items, CleanItem{path, kind, size, fi.ModTime()})
+	}
+
+	entries, err := ioutil.ReadDir(opts.TempDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot read temporary directory")
+	}
+	for _, fi := range entries {
+		path := filepath.Join(opts.TempDir, fi.Name())
+		age := now.Sub(fi.ModTime())
+		switch {
+		case strings.HasPrefix(fi.Name(), "gobackup-"):
+			// Manifest is removed together with its archive
+			if strings.HasSuffix(fi.Name(), ManifestExt) || inUse[path] || age < opts.BackupAge {
+				continue
+			}
+			add(path, fi, CleanBackup)
+		case strings.HasPrefix(fi.Name(), "goup-") && age >= StaleTempAge:
+			add(path, fi, CleanTemp)
+		}
+	}
+
+	entries, err = ioutil.ReadDir(DownloadsDir())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, fi := range entries {
+		path := filepath.Join(DownloadsDir(), fi.Name())
+		switch {
+		case strings.HasSuffix(fi.Name(), ".part"):
+			if now.Sub(fi.ModTime()) >= StaleTempAge {
+				add(path, fi, CleanPartial)
+			}
+		case fi.Mode().IsRegular():
+			add(path, fi, CleanDownload)
+		}
+	}
+
+	// Staging directories and hardlinked backups are created next to
+	// installation roots
+	dirs := []string{VersionsDir()}
+	for _, root := range opts.Roots {
+		dirs = append(dirs, filepath.Dir(filepath.Clean(root)))
+	}
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		matches, _ := filepath.Glob(filepath.Join(dir, ".*.staging-*"))
+		for _, path := range matches {
+			fi, err := os.Lstat(path)
+			if err != nil || seen[path] || !fi.IsDir() || now.Sub(fi.ModTime()) < StaleTempAge {
+				continue
+			}
+			seen[path] = true
+			add(path, fi, CleanStaging)
+		}
+		matches, _ = filepath.Glob(filepath.Join(dir, ".gobackup-*"))
+		for _, path := range matches {
+			fi, err := os.Lstat(path)
+			if err != nil || seen[path] || !fi.IsDir() || inUse[path] || now.Sub(fi.ModTime()) < opts.BackupAge {
+				continue
+			}
+			seen[path] = true
+			add(path, fi, CleanBackup)
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].ModTime.Before(items[j].ModTime)
+	})
+	return items, nil
+}
+
+// Remove deletes the item
+func (item CleanItem) Remove() error {
+	if item.Kind == CleanBackup {
+		return RemoveBackup(item.Path)
+	}
+	return os.RemoveAll(item.Path)
+}
+
+// PruneBuildCache removes entries of Go build cache in dir not used for age,
+// returning their total size. Go refreshes modification time of an entry
+// when it is used, and trims unused entries the same way itself. Entries are
+// only counted if dryRun is true.
+func PruneBuildCache(dir string, age time.Duration, dryRun bool) (int64, error) {
+	if _, err := os.Stat(filepath.Join(dir, "README")); err != nil {
+		return 0, errors.Errorf("%s is not a Go build cache", dir)
+	}
+	cutoff := time.Now().Add(-age)
+	var freed int64
+	// Entries are in subdirectories named by first byte of their hash
+	subdirs, err := filepath.Glob(filepath.Join(dir, "[0-9a-f][0-9a-f]"))
+	if err != nil {
+		return 0, err
+	}
+	for _, sub := range subdirs {
+		entries, err := ioutil.ReadDir(sub)
+		if err != nil {
+			return freed, err
+		}
+		for _, fi := range entries {
+			if !fi.Mode().IsRegular() || !fi.ModTime().Before(cutoff) {
+				continue
+			}
+			if !dryRun {
+				if err := os.Remove(filepath.Join(sub, fi.Name())); err != nil {
+					return freed, err
+				}
+			}
+			freed += fi.Size()
+		}
+	}
+	return freed, nil
+}
+
+// CleanGoCache runs go clean with flag, -cache or -modcache, using go
+// executable goExe
+func CleanGoCache(goExe, flag string) error {
+	if out, err := goCommand(goExe, "clean", flag).CombinedOutput(); err != nil {
+		return errors.Errorf("go clean %s failed: %v\n%s", flag, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
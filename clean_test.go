@@ -0,0 +1,156 @@
+package goup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFindLeftovers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goup-clean")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	setGoupHome(t, filepath.Join(dir, "home"))
+	tmp := filepath.Join(dir, "tmp")
+	root := filepath.Join(dir, "usr", "go")
+
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	files := []struct {
+		path string
+		old  bool
+	}{
+		{filepath.Join(tmp, "gobackup-1.21.1-1", "VERSION"), true},
+		{filepath.Join(tmp, "gobackup-1.21.2-2", "VERSION"), true},
+		{filepath.Join(tmp, "gobackup-1.21.3-3.tar.gz"), true},
+		{filepath.Join(tmp, "gobackup-1.21.3-3.tar.gz"+ManifestExt), true},
+		{filepath.Join(tmp, "gobackup-1.22.0-4", "VERSION"), false},
+		{filepath.Join(tmp, "goup-verified-1"), true},
+		{filepath.Join(tmp, "goup-verified-2"), false},
+		{filepath.Join(tmp, "unrelated"), true},
+		{filepath.Join(DownloadsDir(), "go1.22.1.linux-amd64.tar.gz"), false},
+		{filepath.Join(DownloadsDir(), "go1.22.2.linux-amd64.tar.gz.123.part"), true},
+		{filepath.Join(DownloadsDir(), "go1.22.3.linux-amd64.tar.gz.456.part"), false},
+		{filepath.Join(VersionsDir(), ".go1.22.1.staging-1", "VERSION"), true},
+		{filepath.Join(dir, "usr", ".go.staging-1", "VERSION"), true},
+		{filepath.Join(dir, "usr", ".go.staging-2", "VERSION"), false},
+		{filepath.Join(dir, "usr", ".gobackup-1.21.0-5", "VERSION"), true},
+		{filepath.Join(dir, "usr", ".gobackup-1.22.0-6", "VERSION"), false},
+	}
+	for _, f := range files {
+		os.MkdirAll(filepath.Dir(f.path), 0755)
+		if err := ioutil.WriteFile(f.path, []byte("1234"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if f.old {
+			os.Chtimes(f.path, old, old)
+			os.Chtimes(filepath.Dir(f.path), old, old)
+		}
+	}
+	// Backup of an interrupted upgrade is needed to recover it
+	if _, err := NewJournal(root, filepath.Join(tmp, "gobackup-1.21.2-2"), mustVersion(t, "1.21.2"), mustVersion(t, "1.21.3")); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := FindLeftovers(CleanOptions{TempDir: tmp, Roots: []string{root}, BackupAge: 7 * 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]CleanKind{
+		filepath.Join(tmp, "gobackup-1.21.1-1"):                               CleanBackup,
+		filepath.Join(tmp, "gobackup-1.21.3-3.tar.gz"):                        CleanBackup,
+		filepath.Join(tmp, "goup-verified-1"):                                 CleanTemp,
+		filepath.Join(DownloadsDir(), "go1.22.1.linux-amd64.tar.gz"):          CleanDownload,
+		filepath.Join(DownloadsDir(), "go1.22.2.linux-amd64.tar.gz.123.part"): CleanPartial,
+		filepath.Join(VersionsDir(), ".go1.22.1.staging-1"):                   CleanStaging,
+		filepath.Join(dir, "usr", ".go.staging-1"):                            CleanStaging,
+		filepath.Join(dir, "usr", ".gobackup-1.21.0-5"):                       CleanBackup,
+	}
+	got := make(map[string]CleanKind)
+	for _, item := range items {
+		got[item.Path] = item.Kind
+		if item.Size != 4 {
+			t.Errorf("Size of %s = %d, want 4", item.Path, item.Size)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("FindLeftovers() found %d items, want %d: %v", len(got), len(want), got)
+	}
+	for path, kind := range want {
+		if got[path] != kind {
+			t.Errorf("FindLeftovers() %s = %q, want %q", path, got[path], kind)
+		}
+	}
+
+	for _, item := range items {
+		if err := item.Remove(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "gobackup-1.21.3-3.tar.gz"+ManifestExt)); !os.IsNotExist(err) {
+		t.Error("Manifest of removed backup archive is kept")
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "gobackup-1.21.2-2")); err != nil {
+		t.Error("Backup of pending upgrade is removed")
+	}
+}
+
+func TestPruneBuildCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goup-clean")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if _, err := PruneBuildCache(dir, time.Hour, false); err == nil {
+		t.Error("PruneBuildCache() accepted directory without README")
+	}
+
+	old := time.Now().Add(-10 * 24 * time.Hour)
+	ioutil.WriteFile(filepath.Join(dir, "README"), []byte("This directory holds cached build artifacts from the Go build system."), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "trim.txt"), []byte("1"), 0644)
+	os.Chtimes(filepath.Join(dir, "trim.txt"), old, old)
+	os.MkdirAll(filepath.Join(dir, "0a"), 0755)
+	os.MkdirAll(filepath.Join(dir, "ff"), 0755)
+	entries := map[string]bool{
+		filepath.Join(dir, "0a", "0a12-a"): true,
+		filepath.Join(dir, "0a", "0a34-d"): false,
+		filepath.Join(dir, "ff", "ff56-d"): true,
+	}
+	for path, isOld := range entries {
+		ioutil.WriteFile(path, []byte("12345"), 0644)
+		if isOld {
+			os.Chtimes(path, old, old)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		dryRun bool
+	}{
+		{"TestCase 1", true},
+		{"TestCase 2", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			freed, err := PruneBuildCache(dir, 5*24*time.Hour, tt.dryRun)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if freed != 10 {
+				t.Errorf("PruneBuildCache() = %d, want 10", freed)
+			}
+			for path, isOld := range entries {
+				_, err := os.Stat(path)
+				if removed := os.IsNotExist(err); removed != (isOld && !tt.dryRun) {
+					t.Errorf("%s removed = %v", path, removed)
+				}
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(dir, "trim.txt")); err != nil {
+		t.Error("trim.txt is removed")
+	}
+}
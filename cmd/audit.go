@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mkishere/goup"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	auditCmd     = kingpin.Command("audit", "List known vulnerabilities affecting local Go. Exits with status 1 if any is found.")
+	auditVulnDB  = auditCmd.Flag("vulndb", "Go vulnerability database URL, directory or zip file.").Default(goup.DefaultVulnDB).String()
+	auditExePath = auditCmd.Arg("path", "Path to Go executable. If omitted, will use go executable on $PATH or Go default installation path").String()
+)
+
+func audit() {
+	_, localVer, _, _, err := localGo(*auditExePath)
+	if err != nil {
+		fmt.Println("Error when getting local Go infomration", err)
+		os.Exit(2)
+	}
+
+	printVerbose("Loading vulnerability database from %s\n", *auditVulnDB)
+	entries, err := goup.LoadVulnDB(*auditVulnDB)
+	if err != nil {
+		fmt.Println("Cannot load vulnerability database:", err)
+		os.Exit(2)
+	}
+
+	advisories := goup.AffectingAdvisories(entries, localVer)
+	if len(advisories) == 0 {
+		fmt.Printf("No known vulnerabilities affect Go %v\n", localVer)
+		return
+	}
+
+	fmt.Printf("Go %v is affected by %d known vulnerabilities:\n", localVer, len(advisories))
+	for _, adv := range advisories {
+		fixed := "not fixed yet"
+		if adv.HasFix {
+			fixed = "fixed in " + adv.Fixed.String()
+		}
+		id := adv.ID
+		if len(adv.Aliases) > 0 {
+			id += " (" + strings.Join(adv.Aliases, ", ") + ")"
+		}
+		fmt.Printf("  %s: %s [%s; %s]\n", id, adv.Summary, strings.Join(adv.Packages, ", "), fixed)
+	}
+	if minFixed, ok := goup.MinimumFixedVersion(advisories); ok {
+		fmt.Printf("Minimum fixed version: %v\n", minFixed)
+	} else {
+		fmt.Println("Some vulnerabilities have no fixed version yet")
+	}
+	os.Exit(1)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mkishere/goup"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	changelogCmd      = kingpin.Command("changelog", "Show release notes between two Go versions.")
+	changelogFrom     = changelogCmd.Arg("from", "Version to start from (exclusive). If omitted, local Go version is used.").String()
+	changelogTo       = changelogCmd.Arg("to", "Version to end at (inclusive). If omitted, latest stable version is used.").String()
+	changelogMarkdown = changelogCmd.Flag("markdown", "Render release notes as markdown.").Bool()
+	notesSource       = kingpin.Flag("notes-source", "URL of go.dev or local directory with a copy of its doc pages.").Default(goup.DefaultNotesSource).String()
+)
+
+func changelog() {
+	from, err := versionArg(*changelogFrom, func() (goup.VersionInfo, error) {
+		_, localVer, _, _, err := localGo("")
+		return localVer, err
+	})
+	if err != nil {
+		fmt.Println("Cannot determine version to start from:", err)
+		os.Exit(1)
+	}
+	to, err := versionArg(*changelogTo, func() (goup.VersionInfo, error) {
+		availVerList, err := goup.LatestVersionInfo()
+		if err != nil {
+			return goup.VersionInfo{}, err
+		}
+		return goup.SelectVersion(goup.VersionInfo{}, availVerList, goup.Policy{Kind: goup.PolicyMinor})
+	})
+	if err != nil {
+		fmt.Println("Cannot determine version to end at:", err)
+		os.Exit(1)
+	}
+	if !showNotes(from, to, *changelogMarkdown) {
+		os.Exit(1)
+	}
+}
+
+// versionArg parses version given in command line, or call fallback if it
+// is empty
+func versionArg(arg string, fallback func() (goup.VersionInfo, error)) (goup.VersionInfo, error) {
+	if arg == "" {
+		return fallback()
+	}
+	if len(arg) > 2 && arg[:2] == "go" {
+		arg = arg[2:]
+	}
+	return goup.ExtractVersionInfo(arg)
+}
+
+// showNotes prints release notes after from up to to. It returns false if
+// they cannot be shown.
+func showNotes(from, to goup.VersionInfo, markdown bool) bool {
+	printVerbose("Reading release notes from %s\n", *notesSource)
+	notes, err := goup.ReleaseNotes(*notesSource, from, to)
+	if err != nil {
+		fmt.Println("Cannot retrieve release notes:", err)
+		return false
+	}
+	if len(notes) == 0 {
+		fmt.Printf("No releases between %v and %v\n", from, to)
+		return true
+	}
+	if err := goup.RenderReleaseNotes(os.Stdout, notes, markdown); err != nil {
+		fmt.Println("Cannot show release notes:", err)
+		return false
+	}
+	return true
+}
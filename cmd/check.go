@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/mkishere/goup"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	checkCmd      = kingpin.Command("check", "Check for a new Go version under the upgrade policy, cache the result and notify.")
+	checkGo       = checkCmd.Arg("path", "Path to Go executable. Defaults to go on $PATH.").String()
+	checkPolicy   = checkCmd.Flag("policy", "Upgrade policy, as for goup upgrade.").Default("patch").String()
+	checkChannel  = checkCmd.Flag("channel", "Release channel: stable, rc or beta.").Default("stable").Enum("stable", "rc", "beta")
+	checkNotify   = checkCmd.Flag("notify", "How to notify about a new version: stderr (banner on next goup run), desktop or webhook. Repeatable.").Default("stderr").Enums("stderr", "desktop", "webhook")
+	checkWebhook  = checkCmd.Flag("webhook", "URL receiving a JSON POST when a new version is found.").String()
+	checkNotCmd   = checkCmd.Flag("notify-command", "Command run with title and message as arguments instead of the desktop notifier.").String()
+	checkAlways   = checkCmd.Flag("always", "Notify on every check, not only when a new version is found first.").Bool()
+	checkVulnDB   = checkCmd.Flag("vulndb", "Go vulnerability database URL, directory or zip file, used by security policy.").Default(goup.DefaultVulnDB).String()
+	checkInterval = checkCmd.Flag("interval", "Keep running and check again after interval, e.g. 24h.").Duration()
+
+	scheduleCmd        = kingpin.Command("schedule", "Run goup check periodically with systemd user timer or cron.")
+	scheduleInstallCmd = scheduleCmd.Command("install", "Create systemd user timer running goup check, or print crontab entry with --cron.")
+	scheduleRemoveCmd  = scheduleCmd.Command("remove", "Remove systemd user timer created by goup schedule install.")
+	scheduleEvery      = scheduleInstallCmd.Flag("every", "How often to check: hourly, daily or weekly.").Default("daily").Enum(goup.Schedules...)
+	scheduleCron       = scheduleInstallCmd.Flag("cron", "Print crontab entry instead of creating systemd units.").Bool()
+	scheduleArgs       = scheduleInstallCmd.Arg("check-args", "Arguments passed to goup check, after --, e.g. -- --notify desktop.").Strings()
+)
+
+// runCheck checks for a new version, caches the result and notifies if
+// it is new
+func runCheck() bool {
+	previous, _ := goup.LoadCheckResult()
+	result := goup.CheckResult{CheckedAt: time.Now(), Policy: *checkPolicy, Notify: *checkNotify, Latest: previous.Latest}
+	goExeFullPath, localVer, _, _, err := localGo(*checkGo)
+	if err == nil {
+		result.Local = localVer
+		env, envErr := goup.ReadGoEnv(goExeFullPath)
+		result.GoRoot = env.GOROOT
+		err = envErr
+	}
+	if err == nil {
+		var latest goup.VersionInfo
+		if latest, err = targetVersion(localVer, *checkPolicy, *checkChannel, *checkVulnDB, false, false, false); err == nil {
+			result.Latest = latest
+		}
+	}
+	if err != nil {
+		result.Error = err.Error()
+	} else {
+		result.UpdateAvailable = result.Latest != localVer
+	}
+	// Keep banner shown once per release
+	result.BannerShown = previous.BannerShown && !result.IsNewUpdate(previous)
+	if err := goup.SaveCheckResult(result); err != nil {
+		fmt.Println(err)
+	}
+	fmt.Println(result.Message())
+
+	ok := result.Error == ""
+	// A failure is told once, not on every check until it is fixed
+	newFailure := !ok && previous.Error == ""
+	if !newFailure && !result.IsNewUpdate(previous) && !(*checkAlways && result.UpdateAvailable) {
+		return ok
+	}
+	for _, n := range *checkNotify {
+		var err error
+		switch n {
+		case "desktop":
+			err = notifyDesktop(result)
+		case "webhook":
+			if *checkWebhook == "" {
+				err = fmt.Errorf("--webhook is not given")
+			} else {
+				err = goup.PostWebhook(*checkWebhook, result)
+			}
+		}
+		if err != nil {
+			fmt.Printf("Cannot notify by %s: %v\n", n, err)
+			ok = false
+		}
+	}
+	return ok
+}
+
+func notifyDesktop(result goup.CheckResult) error {
+	title := "Go update available"
+	if result.Error != "" {
+		title = "goup check failed"
+	}
+	args := []string{*checkNotCmd, title, result.Message()}
+	if *checkNotCmd == "" {
+		var err error
+		if args, err = goup.DesktopNotifyCommand(title, result.Message()); err != nil {
+			return err
+		}
+	}
+	if out, err := exec.Command(args[0], args[1:]...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func check() {
+	for *checkInterval > 0 {
+		runCheck()
+		time.Sleep(*checkInterval)
+	}
+	if !runCheck() {
+		os.Exit(1)
+	}
+}
+
+// showUpdateBanner tells on stderr about a new version found by a background
+// check, once per release
+func showUpdateBanner() {
+	result, err := goup.LoadCheckResult()
+	if err != nil || !result.UpdateAvailable || result.BannerShown || !containsString(result.Notify, "stderr") {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "goup: %s\n", result.Message())
+	result.BannerShown = true
+	goup.SaveCheckResult(result)
+}
+
+// systemdUserDir is where systemd looks for user units
+func systemdUserDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "systemd", "user")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "systemd", "user")
+}
+
+func scheduleInstall() {
+	self, err := os.Executable()
+	if err == nil {
+		self, err = filepath.Abs(self)
+	}
+	if err != nil {
+		fmt.Println("Cannot locate goup executable:", err)
+		os.Exit(1)
+	}
+	args := append([]string{"check"}, *scheduleArgs...)
+	if *scheduleCron || runtime.GOOS != "linux" {
+		fmt.Println("Add this line to your crontab with crontab -e:")
+		fmt.Println(goup.CronEntry(self, args, *scheduleEvery))
+		return
+	}
+	service, timer := goup.SystemdUnits(self, args, *scheduleEvery)
+	dir := systemdUserDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	for name, content := range map[string]string{"goup-check.service": service, "goup-check.timer": timer} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			fmt.Println("Cannot write systemd unit:", err)
+			os.Exit(1)
+		}
+	}
+	fmt.Printf("Created goup-check.service and goup-check.timer in %s\n", dir)
+	fmt.Println("Start the timer with:")
+	fmt.Println("  systemctl --user daemon-reload && systemctl --user enable --now goup-check.timer")
+}
+
+func scheduleRemove() {
+	dir := systemdUserDir()
+	// systemd keeps a running timer after its unit file is gone, so stop it
+	// while the unit is still there
+	if _, err := os.Stat(filepath.Join(dir, "goup-check.timer")); err == nil {
+		if err := systemctlUser("disable", "--now", "goup-check.timer"); err != nil {
+			fmt.Println("Cannot stop goup-check timer:", err)
+			fmt.Println("Stop it with systemctl --user disable --now goup-check.timer and run goup schedule remove again.")
+			os.Exit(1)
+		}
+	}
+	removed := false
+	for _, name := range []string{"goup-check.timer", "goup-check.service"} {
+		if err := os.Remove(filepath.Join(dir, name)); err == nil {
+			removed = true
+		} else if !os.IsNotExist(err) {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+	if !removed {
+		fmt.Println("No goup systemd timer found. Remove cron entry with crontab -e if you added one.")
+		return
+	}
+	if err := systemctlUser("daemon-reload"); err != nil {
+		fmt.Println("Cannot reload systemd, run systemctl --user daemon-reload:", err)
+	}
+	fmt.Println("Stopped and removed goup-check timer")
+}
+
+// systemctlUser runs systemctl on user units. Without systemctl no timer
+// can be running, so there is nothing to do.
+func systemctlUser(args ...string) error {
+	systemctl, err := exec.LookPath("systemctl")
+	if err != nil {
+		printVerbose("Skip systemctl %s: %v\n", strings.Join(args, " "), err)
+		return nil
+	}
+	output, err := exec.Command(systemctl, append([]string{"--user"}, args...)...).CombinedOutput()
+	if err != nil && len(output) > 0 {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return err
+}
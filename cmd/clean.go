@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mkishere/goup"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	cleanCmd       = kingpin.Command("clean", "Remove goup leftovers and trim Go build cache.")
+	cleanDryRun    = cleanCmd.Flag("dry-run", "List what would be removed without removing anything.").Short('n').Bool()
+	cleanGo        = cleanCmd.Flag("go", "Go executable whose caches are checked.").Default("go").String()
+	cleanCache     = cleanCmd.Flag("cache", "Trim Go build cache, entirely or only entries unused for --cache-age.").Bool()
+	cleanCacheAge  = cleanCmd.Flag("cache-age", "Remove only build cache entries unused for this long, e.g. 120h.").Duration()
+	cleanModCache  = cleanCmd.Flag("modcache", "Remove Go module cache too.").Bool()
+	cleanBackupAge = cleanCmd.Flag("backup-age", "Keep backups of Go installation newer than this.").Default("168h").Duration()
+
+	cleanAfterUpg = upgradeCmd.Flag("clean", "Remove old backups and goup leftovers and clear Go build cache after upgrading.").Bool()
+)
+
+func clean() {
+	env, err := goup.ReadGoEnv(*cleanGo)
+	if err != nil {
+		printVerbose("Cannot read Go environment: %v\n", err)
+	}
+	opts := goup.CleanOptions{Roots: []string{goup.UserGoRoot()}, BackupAge: *cleanBackupAge}
+	if env.GOROOT != "" {
+		opts.Roots = append(opts.Roots, env.GOROOT)
+	}
+	ok := removeLeftovers(opts, *cleanDryRun)
+
+	for _, c := range []struct{ name, dir string }{{"GOCACHE", env.GOCACHE}, {"GOMODCACHE", env.GOMODCACHE}} {
+		if c.dir == "" {
+			continue
+		}
+		size, _ := goup.DirSize(c.dir)
+		fmt.Printf("%-10s %s  %s\n", c.name, goup.FormatBytes(size), c.dir)
+	}
+	if *cleanCache && env.GOCACHE != "" {
+		if *cleanCacheAge > 0 {
+			freed, err := goup.PruneBuildCache(env.GOCACHE, *cleanCacheAge, *cleanDryRun)
+			if err != nil {
+				fmt.Println("Cannot trim build cache:", err)
+				ok = false
+			}
+			fmt.Printf("Build cache entries unused for %v: %s%s\n", *cleanCacheAge, goup.FormatBytes(freed), dryRunNote(*cleanDryRun))
+		} else {
+			ok = runGoClean(env.Executable, "-cache", *cleanDryRun) && ok
+		}
+	}
+	if *cleanModCache && env.GOMODCACHE != "" {
+		ok = runGoClean(env.Executable, "-modcache", *cleanDryRun) && ok
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+// removeLeftovers lists and, unless dryRun, removes goup leftovers. Returns
+// false if any cannot be removed.
+func removeLeftovers(opts goup.CleanOptions, dryRun bool) bool {
+	items, err := goup.FindLeftovers(opts)
+	if err != nil {
+		fmt.Println("Cannot look for leftovers:", err)
+		return false
+	}
+	if len(items) == 0 {
+		fmt.Println("No goup leftovers found")
+		return true
+	}
+	ok := true
+	var total int64
+	for _, item := range items {
+		fmt.Printf("%-16s %10s  %s  %s\n", item.Kind, goup.FormatBytes(item.Size), item.ModTime.Format("2006-01-02"), item.Path)
+		if dryRun {
+			total += item.Size
+			continue
+		}
+		if err := item.Remove(); err != nil {
+			fmt.Printf("Cannot remove %s: %v\n", item.Path, err)
+			ok = false
+			continue
+		}
+		total += item.Size
+	}
+	if dryRun {
+		fmt.Printf("%s would be freed\n", goup.FormatBytes(total))
+	} else {
+		fmt.Printf("%s freed\n", goup.FormatBytes(total))
+	}
+	return ok
+}
+
+// runGoClean runs go clean with flag after showing what it removes
+func runGoClean(goExe, flag string, dryRun bool) bool {
+	if dryRun {
+		fmt.Printf("Would run %s clean %s\n", goExe, flag)
+		return true
+	}
+	fmt.Printf("Running %s clean %s\n", goExe, flag)
+	if err := goup.CleanGoCache(goExe, flag); err != nil {
+		fmt.Println(err)
+		return false
+	}
+	return true
+}
+
+func dryRunNote(dryRun bool) string {
+	if dryRun {
+		return " (dry run, nothing removed)"
+	}
+	return " removed"
+}
+
+// cleanAfterUpgrade removes leftovers of previous upgrades and build cache of
+// the replaced toolchain, which new Go never uses
+func cleanAfterUpgrade(goExe, gopath string) {
+	fmt.Println("Cleaning up")
+	// Backups of the last week are kept for going back manually
+	removeLeftovers(goup.CleanOptions{Roots: []string{gopath}, BackupAge: 7 * 24 * time.Hour}, false)
+	runGoClean(goExe, "-cache", false)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mkishere/goup"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	doctorCmd     = kingpin.Command("doctor", "Diagnose Go installations on PATH and goup state, suggesting fixes.")
+	doctorOffline = doctorCmd.Flag("offline", "Do not check the version source and download site are reachable.").Bool()
+)
+
+func doctor() {
+	opts := goup.DoctorOptions{Path: os.Getenv("PATH")}
+	if !*doctorOffline {
+		// Probe the archive an upgrade of current Go would download
+		_, localVer, platform, arch, err := localGo("")
+		if err != nil {
+			localVer = goup.VersionInfo{}
+		}
+		opts.Sources = goup.DefaultSources(localVer, platform, arch)
+	}
+	problems, warnings := 0, 0
+	for _, f := range goup.Doctor(opts) {
+		fmt.Printf("%-10s %s: %s\n", "["+f.Severity.String()+"]", f.Check, f.Message)
+		if f.Fix != "" {
+			fmt.Printf("%-10s fix: %s\n", "", f.Fix)
+		}
+		switch f.Severity {
+		case goup.SeverityProblem:
+			problems++
+		case goup.SeverityWarning:
+			warnings++
+		}
+	}
+	fmt.Printf("%d problems, %d warnings\n", problems, warnings)
+	if problems > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"syscall"
+)
+
+// execTool replaces goup process with the tool
+func execTool(exe string, args, env []string) error {
+	return syscall.Exec(exe, args, env)
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+)
+
+// execTool runs the tool and exits with its exit code, as Windows cannot
+// replace a running process
+func execTool(exe string, args, env []string) error {
+	cmd := exec.Command(exe, args[1:]...)
+	cmd.Env = env
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			os.Exit(exitErr.ExitCode())
+		}
+		return err
+	}
+	os.Exit(0)
+	return nil
+}
@@ -1,193 +1,490 @@
-package main
-
-import (
-	"fmt"
-	"io"
-	"io/ioutil"
-	"os"
-	"path/filepath"
-
-	"github.com/mkishere/goup"
-	kingpin "gopkg.in/alecthomas/kingpin.v2"
-	pb "gopkg.in/cheggaaa/pb.v1"
-)
-
-var (
-	verbose   = kingpin.Flag("verbose", "Prints verbose messages.").Short('v').Bool()
-	incBeta   = kingpin.Flag("beta", "Include Beta in list of consideration. True if local version is beta.").Short('b').Bool()
-	incRC     = kingpin.Flag("rc", "Include Release Candidate in list of consideration. True if local version is RC.").Short('c').Bool()
-	autoUpd   = kingpin.Flag("silent", "Auto download and upgrade local Go without confirmation.").Short('s').Bool()
-	goExePath = kingpin.Arg("path", "Path to Go executable. If omitted, will use\n1. go executable on $PATH\n2. Go default installation path").String()
-	jumpVer   = kingpin.Flag("upgrade", "Jump to latest version if available. If not set, will only update to latest build.").Short('u').Bool()
-)
-
-func main() {
-	kingpin.Parse()
-	goExeFullPath := filepath.Join(*goExePath, "go")
-
-	printVerbose("Running command \"%v version\"\n", goExeFullPath)
-	localVer, platform, arch, err := goup.LocalGoInfo(goExeFullPath)
-	if err != nil {
-		// Try default path
-		printVerbose("Trying default installation directory %s", goup.DefaultInstallDir)
-		goExeFullPath = filepath.Join(goup.DefaultInstallDir, "go")
-		localVer, platform, arch, err = goup.LocalGoInfo(goExeFullPath)
-		if err != nil {
-			fmt.Println("Error when getting local Go infomration", err)
-			return
-		}
-	}
-
-	printVerbose("Running command \"%v env\"\n", goExeFullPath)
-	gopath, err := goup.GoPath(goExeFullPath)
-	if err != nil {
-		fmt.Println("Error when getting local Go infomration", err)
-		return
-	}
-
-	printVerbose("Local Go Info:(Version:%v, OS:%v, Arch:%v, GoHome:%v)\n", localVer, platform, arch, gopath)
-
-	availVerList, err := goup.LatestVersionInfo()
-	if err != nil {
-		fmt.Println("Cannot retrieve version information", err)
-	}
-
-	// Assume user will like beta and RC if they are already using beta/RC
-	if localVer.Beta {
-		*incBeta = true
-		*incRC = true
-	}
-	if localVer.RC {
-		*incRC = true
-	}
-
-	var latestVer goup.VersionInfo
-	for i := range availVerList {
-		if !*incRC && availVerList[i].RC {
-			continue
-		}
-		if !*incBeta && availVerList[i].Beta {
-			continue
-		}
-		if !*jumpVer && (availVerList[i].Major != localVer.Major || availVerList[i].Minor != localVer.Minor) {
-			continue
-		}
-		latestVer = availVerList[i]
-		break
-	}
-
-	fmt.Printf("Latest version is %v\n", latestVer)
-	if latestVer == localVer {
-		fmt.Println("Your Go is at latest version. Exiting...")
-		return
-	}
-
-	if !*autoUpd {
-		var input string
-	loop:
-		for {
-			fmt.Print("Do you want to download and upgrade now (Y/n):")
-			fmt.Scanln(&input)
-			switch input {
-			case "Y", "y":
-				break loop
-			case "n", "N":
-				return
-			}
-		}
-	}
-
-	latestGoBin, err := ioutil.TempFile("", "go"+latestVer.String()+arch+platform)
-	if err != nil {
-		fmt.Println("Cannot create temporary file:", err)
-		return
-	}
-	defer latestGoBin.Close()
-
-	dlUrl := goup.DownloadUrl(latestVer, platform, arch)
-	fmt.Printf("Downloading from %s\n", dlUrl)
-	fileSize, err := goup.DownloadPackage(dlUrl,
-		func(totalSize int64, src io.Reader) error {
-			// Create a progress bar in console for download
-			bar := pb.New(int(totalSize)).SetUnits(pb.U_BYTES)
-			bar.Start()
-			reader := bar.NewProxyReader(src)
-			defer reader.Close()
-			_, err := io.Copy(latestGoBin, reader)
-			if err != nil {
-				fmt.Println("\nError occured while downloading:", err)
-				return err
-			}
-			bar.FinishPrint("Download completed")
-			return nil
-		})
-
-	if err != nil {
-		fmt.Println("Cannot download file: ", err)
-		return
-	}
-
-	// Backup current Go installation to temp directory
-	fmt.Println("Backing up current Go to temporary directory")
-	backupDir, err := ioutil.TempDir("", "gobackup-"+localVer.String()+"-")
-	printVerbose("Backup location: %s\n", backupDir)
-	if err != nil {
-		fmt.Println("Error creating backup in temporary directory:", err)
-		return
-	}
-	err = goup.RecursiveCopyDir(gopath, backupDir)
-	if err != nil {
-		fmt.Println("Error backing up in temporary directory:", err)
-		return
-	}
-
-	// Remove current Go installation
-	err = os.RemoveAll(gopath)
-	printVerbose("Removing %s\n", gopath)
-	if err != nil {
-		fmt.Println("Error removing existing Go directory. Make sure goup runs with elevated permissions:", err)
-		return
-	}
-	// Extract archive
-	fmt.Printf("Extracting latest Go to %s\n", gopath)
-	err = goup.ExtractArchive(latestGoBin, fileSize, gopath, printVerbose)
-	if err != nil {
-		printVerbose("Error: %v\n", err)
-		fmt.Println("Error extracting new Go package, restoring...")
-		err = restore(backupDir, gopath)
-		if err != nil {
-			fmt.Println("Unrecoverable error, please consider reinstall Go manually ", err)
-		}
-		return
-	}
-
-	// Verify
-	newLocalVer, _, _, err := goup.LocalGoInfo(goExeFullPath)
-	if err != nil || newLocalVer != latestVer {
-		printVerbose("Error: %v\n", err)
-		err = restore(backupDir, gopath)
-		if err != nil {
-			fmt.Println("Unrecoverable error, please consider reinstall Go manually ", err)
-			return
-		}
-	}
-}
-
-func restore(backupPath, goPath string) (err error) {
-	err = os.RemoveAll(goPath)
-	if err != nil {
-		return
-	}
-	err = os.MkdirAll(goPath, 0755)
-	if err != nil {
-		return
-	}
-	err = goup.RecursiveCopyDir(backupPath, goPath)
-	return
-}
-
-func printVerbose(format string, a ...interface{}) {
-	if *verbose {
-		fmt.Printf(format, a...)
-	}
-}
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/mkishere/goup"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+	pb "gopkg.in/cheggaaa/pb.v1"
+)
+
+var (
+	verbose = kingpin.Flag("verbose", "Prints verbose messages.").Short('v').Bool()
+
+	upgradeCmd = kingpin.Command("upgrade", "Check and upgrade local Go (default command).").Default()
+	incBeta    = upgradeCmd.Flag("beta", "Include Beta in list of consideration. True if local version is beta.").Short('b').Bool()
+	incRC      = upgradeCmd.Flag("rc", "Include Release Candidate in list of consideration. True if local version is RC.").Short('c').Bool()
+	autoUpd    = upgradeCmd.Flag("silent", "Auto download and upgrade local Go without confirmation.").Short('s').Bool()
+	goExePath  = upgradeCmd.Arg("path", "Path to Go executable. If omitted, will use\n1. go executable on $PATH\n2. Go default installation path").String()
+	jumpVer    = upgradeCmd.Flag("upgrade", "Jump to latest version if available. Same as --policy=minor, and cannot be used with other policies.").Short('u').Bool()
+	policyStr  = upgradeCmd.Flag("policy", "Upgrade policy: patch, minor, supported, n-1, security or pinned:<constraint> (e.g. pinned:>=1.20,<1.22).").Default("patch").String()
+	channel    = upgradeCmd.Flag("channel", "Release channel: stable, rc or beta.").Default("stable").Enum("stable", "rc", "beta")
+	showNotesF = upgradeCmd.Flag("show-notes", "Show release notes between local and target version before upgrading.").Bool()
+	backupStr  = upgradeCmd.Flag("backup-strategy", "How backup copies files: auto (copy-on-write clone if supported), copy or hardlink (backup next to Go installation).").Default("auto").Enum("auto", "copy", "hardlink")
+	backupFmt  = upgradeCmd.Flag("backup-format", "Backup as a directory copy (dir) or a compressed archive with checksum manifest (tar.gz).").Default("dir").Enum("dir", "tar.gz")
+	backupJobs = upgradeCmd.Flag("backup-workers", "Number of files copied concurrently when backing up. Defaults to number of CPUs.").Int()
+	waitLock   = upgradeCmd.Flag("wait", "Wait for other goup upgrading the same Go installation to finish instead of exiting.").Bool()
+	privMode   = upgradeCmd.Flag("privilege", "When Go installation is not writable: ask, sudo (install with sudo or doas), user (install for current user only) or fail.").Default("ask").Enum("ask", "sudo", "user", "fail")
+	noPreflgt  = upgradeCmd.Flag("no-preflight", "Skip checking permissions and free space before downloading.").Bool()
+	streamUpg  = upgradeCmd.Flag("stream", "Extract new Go next to current installation while downloading, without a temporary archive. tar.gz releases only.").Bool()
+	vulnDB     = upgradeCmd.Flag("vulndb", "Go vulnerability database URL, directory or zip file, used by security policy.").Default(goup.DefaultVulnDB).String()
+)
+
+func main() {
+	// Invoked through go or gofmt shim
+	if tool := goup.ShimTool(os.Args[0]); tool != "" {
+		runShim(tool)
+		return
+	}
+
+	os.Args = initDashArgs(os.Args)
+	command := kingpin.Parse()
+	// Only commands changing Go installations need them consistent. env and
+	// init run from shell prompt hooks and must never stop for a question.
+	switch command {
+	case upgradeCmd.FullCommand(), installCmd.FullCommand(),
+		removeCmd.FullCommand(), cleanCmd.FullCommand():
+		recoverPendingUpgrades("")
+	case applyCmd.FullCommand():
+		// apply runs as root for another user and shares journals of that
+		// user, but must leave other installations of that user alone
+		goup.SetJournalDir(*applyJournal)
+		recoverPendingUpgrades(*applyRoot)
+	}
+	// Prompt hooks and checks have no user to tell
+	switch command {
+	case checkCmd.FullCommand(), statusCmd.FullCommand(), envCmd.FullCommand(), initCmd.FullCommand(),
+		// self-update runs version to test a download
+		versionCmd.FullCommand(), applyCmd.FullCommand():
+	default:
+		showUpdateBanner()
+	}
+
+	switch command {
+	case upgradeCmd.FullCommand():
+		upgrade()
+	case auditCmd.FullCommand():
+		audit()
+	case changelogCmd.FullCommand():
+		changelog()
+	case downloadCmd.FullCommand():
+		download()
+	case installCmd.FullCommand():
+		install()
+	case applyCmd.FullCommand():
+		apply()
+	case shimInstallCmd.FullCommand():
+		shimInstall()
+	case whichCmd.FullCommand():
+		which()
+	case defaultCmd.FullCommand():
+		defaultVersion()
+	case envCmd.FullCommand():
+		shellEnv()
+	case initCmd.FullCommand():
+		initHook()
+	case execCmd.FullCommand():
+		execVersion()
+	case toolsListCmd.FullCommand():
+		toolsList()
+	case toolsRebuildCmd.FullCommand():
+		toolsRebuild()
+	case cleanCmd.FullCommand():
+		clean()
+	case doctorCmd.FullCommand():
+		doctor()
+	case removeCmd.FullCommand():
+		remove()
+	case checkCmd.FullCommand():
+		check()
+	case scheduleInstallCmd.FullCommand():
+		scheduleInstall()
+	case scheduleRemoveCmd.FullCommand():
+		scheduleRemove()
+	case statusCmd.FullCommand():
+		status()
+	case selfUpdateCmd.FullCommand():
+		selfUpdate()
+	case versionCmd.FullCommand():
+		fmt.Println(version)
+	}
+}
+
+// localGo locates go executable in path, falling back to default installation
+// directory, and returns its version, OS and arch. Development builds are
+// rejected as they have no release to upgrade from.
+func localGo(path string) (goExeFullPath string, localVer goup.VersionInfo, platform, arch string, err error) {
+	goExeFullPath = filepath.Join(path, "go")
+	if exe, lookErr := exec.LookPath(goExeFullPath); lookErr == nil {
+		if filepath.Dir(exe) == goup.ShimsDir() {
+			err = fmt.Errorf("%s is a goup shim, add Go versions with \"goup install\" or pass the path of another Go", exe)
+			return
+		}
+		// Callers run go in other directories
+		if abs, absErr := filepath.Abs(exe); absErr == nil {
+			goExeFullPath = abs
+		}
+	}
+
+	printVerbose("Running command \"%v version\"\n", goExeFullPath)
+	gv, err := goup.LocalGoVersion(goExeFullPath)
+	if err != nil {
+		// Try default path
+		printVerbose("Trying default installation directory %s\n", goup.DefaultInstallDir)
+		goExeFullPath = filepath.Join(goup.DefaultInstallDir, "go")
+		gv, err = goup.LocalGoVersion(goExeFullPath)
+	}
+	if err != nil {
+		return
+	}
+	if gv.Devel {
+		err = fmt.Errorf("%s is a development build (commit %s), which goup does not upgrade", goExeFullPath, gv.DevelCommit)
+		return
+	}
+	if gv.Vendor != "" || len(gv.Experiments) > 0 {
+		printVerbose("Go build information: vendor %q, experiments %v\n", gv.Vendor, gv.Experiments)
+	}
+	return goExeFullPath, gv.Version, gv.OS, gv.Arch, nil
+}
+
+func upgrade() {
+	goExeFullPath, localVer, platform, arch, err := localGo(*goExePath)
+	if err != nil {
+		fmt.Println("Error when getting local Go infomration", err)
+		return
+	}
+
+	printVerbose("Running command \"%v env -json\"\n", goExeFullPath)
+	goEnv, err := goup.ReadGoEnv(goExeFullPath)
+	if err != nil {
+		fmt.Println("Error when getting local Go infomration", err)
+		return
+	}
+	gopath := goEnv.GOROOT
+
+	printVerbose("Local Go Info:(Version:%v, OS:%v, Arch:%v, GoHome:%v, Executable:%v)\n", localVer, platform, arch, gopath, goEnv.Executable)
+
+	latestVer, err := targetVersion(localVer, *policyStr, *channel, *vulnDB, *jumpVer, *incRC, *incBeta)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Printf("Latest version is %v\n", latestVer)
+	if latestVer == localVer {
+		fmt.Println("Your Go is at latest version. Exiting...")
+		return
+	}
+	if *showNotesF {
+		showNotes(localVer, latestVer, false)
+	}
+
+	// Install phase needs other privileges if current user cannot modify Go
+	if !goup.CanModify(gopath) {
+		switch choosePrivilege(gopath, latestVer) {
+		case "sudo":
+			elevateUpgrade(gopath, localVer, latestVer, platform, arch)
+		case "user":
+			userInstall(latestVer, platform, arch)
+		default:
+			fmt.Printf("Cannot modify %s as current user. Use --privilege sudo or --privilege user\n", gopath)
+		}
+		return
+	}
+
+	if !*noPreflgt {
+		backupSize, _ := goup.DirSize(gopath)
+		backupDir := ""
+		switch {
+		case *backupFmt == "tar.gz":
+			backupSize /= 3
+		case *backupStr == "hardlink":
+			// Links take no space, but only if they can be made
+			backupDir = filepath.Dir(filepath.Clean(gopath))
+			if goup.SameFileSystem(gopath, backupDir) {
+				backupSize = 0
+			}
+		}
+		if err := preflight(gopath, "", backupDir, latestVer, platform, arch, *streamUpg, backupSize); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+
+	if !*autoUpd && !confirm("Do you want to download and upgrade now (Y/n):") {
+		return
+	}
+
+	// Prevent other goup from upgrading the same Go installation
+	waitMsgShown := false
+	lock, err := goup.LockInstallRoot(gopath, *waitLock, func(lockedErr *goup.LockedError) {
+		if !waitMsgShown {
+			fmt.Printf("Waiting as %v...\n", lockedErr)
+			waitMsgShown = true
+		}
+	})
+	if err != nil {
+		if _, ok := err.(*goup.LockedError); ok {
+			fmt.Printf("Cannot upgrade %s: %v. Use --wait to wait for it to finish\n", gopath, err)
+		} else {
+			fmt.Println("Cannot lock Go installation directory:", err)
+		}
+		return
+	}
+	defer lock.Unlock()
+
+	var latestGoBin *os.File
+	var fileSize int64 = -1
+	var staging string
+	if *streamUpg {
+		staging, err = streamRelease(latestVer, platform, arch, gopath)
+		if err != nil {
+			fmt.Println("Cannot download file: ", err)
+			return
+		}
+		defer os.RemoveAll(staging)
+	} else {
+		latestGoBin, err = ioutil.TempFile("", "goup-go"+latestVer.String()+arch+platform)
+		if err != nil {
+			fmt.Println("Cannot create temporary file:", err)
+			return
+		}
+		defer latestGoBin.Close()
+
+		dlUrl := goup.DownloadUrl(latestVer, platform, arch)
+		fmt.Printf("Downloading from %s\n", dlUrl)
+		fileSize, err = goup.DownloadPackage(dlUrl,
+			func(totalSize int64, src io.Reader) error {
+				// Create a progress bar in console for download
+				bar := pb.New(int(totalSize)).SetUnits(pb.U_BYTES)
+				bar.Start()
+				reader := bar.NewProxyReader(src)
+				defer reader.Close()
+				_, err := io.Copy(latestGoBin, reader)
+				if err != nil {
+					fmt.Println("\nError occured while downloading:", err)
+					return err
+				}
+				bar.FinishPrint("Download completed")
+				return nil
+			})
+
+		if err != nil {
+			fmt.Println("Cannot download file: ", err)
+			return
+		}
+	}
+
+	ok := applyUpgrade(gopath, goExeFullPath, localVer, latestVer, latestGoBin, fileSize, staging, backupOptions{*backupFmt, *backupStr, *backupJobs})
+	recordUpgrade(gopath, localVer, latestVer, ok)
+	if ok {
+		if *cleanAfterUpg {
+			cleanAfterUpgrade(goExeFullPath, gopath)
+		}
+		offerToolRebuild(goExeFullPath)
+	}
+}
+
+// targetVersion selects the version to upgrade localVer to under policy and
+// channel, including RC and beta if asked for or local version is one
+func targetVersion(localVer goup.VersionInfo, policyStr, channelStr, vulnSource string, jump, rc, beta bool) (goup.VersionInfo, error) {
+	policy, err := goup.ParsePolicy(policyStr)
+	if err != nil {
+		return goup.VersionInfo{}, err
+	}
+	if jump {
+		// Other policies, e.g. a pin, would be silently ignored
+		if policy.Kind != goup.PolicyPatch && policy.Kind != goup.PolicyMinor {
+			return goup.VersionInfo{}, fmt.Errorf("--upgrade cannot be used with --policy %s", policyStr)
+		}
+		policy.Kind = goup.PolicyMinor
+	}
+	policy.Channel, err = goup.ParseChannel(channelStr)
+	if err != nil {
+		return goup.VersionInfo{}, err
+	}
+
+	// Assume user will like beta and RC if they are already using beta/RC
+	if rc || localVer.RC {
+		policy.Channel = maxChannel(policy.Channel, goup.ChannelRC)
+	}
+	if beta || localVer.Beta {
+		policy.Channel = goup.ChannelBeta
+	}
+
+	availVerList, err := goup.LatestVersionInfo()
+	if err != nil {
+		return goup.VersionInfo{}, fmt.Errorf("Cannot retrieve version information: %v", err)
+	}
+
+	if policy.Kind == goup.PolicySecurity {
+		printVerbose("Loading vulnerability database from %s\n", vulnSource)
+		entries, err := goup.LoadVulnDB(vulnSource)
+		if err != nil {
+			return goup.VersionInfo{}, fmt.Errorf("Cannot load vulnerability database: %v", err)
+		}
+		policy.Advisories = goup.AffectingAdvisories(entries, localVer)
+		for _, adv := range policy.Advisories {
+			fmt.Printf("Go %v is affected by %s\n", localVer, adv.ID)
+		}
+	}
+
+	printVerbose("Upgrade policy: %v, channel: %v\n", policy, policy.Channel)
+	return goup.SelectVersion(localVer, availVerList, policy)
+}
+
+// backupOptions controls how applyUpgrade backs up current installation
+type backupOptions struct {
+	format   string
+	strategy string
+	workers  int
+}
+
+// applyUpgrade replaces Go installation at gopath with new version from
+// archive, or staging directory if not empty. Current installation is backed
+// up and restored if anything fails. Returns true on success.
+func applyUpgrade(gopath, goExeFullPath string, localVer, latestVer goup.VersionInfo, latestGoBin *os.File, fileSize int64, staging string, opts backupOptions) bool {
+	// Backup current Go installation to temp directory
+	fmt.Println("Backing up current Go")
+	backupPath, err := newBackupPath(gopath, localVer, opts)
+	printVerbose("Backup location: %s\n", backupPath)
+	if err != nil {
+		fmt.Println("Error creating backup:", err)
+		return false
+	}
+	journal, err := goup.NewJournal(gopath, backupPath, localVer, latestVer)
+	if err != nil {
+		fmt.Println("Cannot start upgrade journal:", err)
+		return false
+	}
+	if goup.IsBackupArchive(backupPath) {
+		err = goup.CreateBackupArchive(gopath, backupPath)
+		if err == nil {
+			printVerbose("Verifying backup\n")
+			err = goup.VerifyBackupArchive(backupPath)
+		}
+	} else {
+		strategy, _ := goup.ParseCopyStrategy(opts.strategy)
+		err = goup.RecursiveCopyDirWithOptions(gopath, backupPath, goup.CopyOptions{Workers: opts.workers, Strategy: strategy,
+			Log: func(format string, arg ...interface{}) { fmt.Printf(format, arg...) }})
+	}
+	if err != nil {
+		fmt.Println("Error backing up:", err)
+		rollback(journal)
+		return false
+	}
+
+	// Remove current Go installation
+	if err = journal.SetPhase(goup.PhaseRemove); err != nil {
+		fmt.Println(err)
+		rollback(journal)
+		return false
+	}
+	err = os.RemoveAll(gopath)
+	printVerbose("Removing %s\n", gopath)
+	if err != nil {
+		fmt.Println("Error removing existing Go directory. Make sure goup runs with elevated permissions:", err)
+		rollback(journal)
+		return false
+	}
+	// Extract archive
+	fmt.Printf("Extracting latest Go to %s\n", gopath)
+	if err = journal.SetPhase(goup.PhaseExtract); err == nil {
+		if staging != "" {
+			err = os.Rename(staging, gopath)
+		} else {
+			err = goup.ExtractArchive(latestGoBin, fileSize, gopath, printVerbose)
+		}
+	}
+	if err != nil {
+		printVerbose("Error: %v\n", err)
+		fmt.Println("Error extracting new Go package, restoring...")
+		rollback(journal)
+		return false
+	}
+
+	// Verify
+	if err = journal.SetPhase(goup.PhaseVerify); err != nil {
+		fmt.Println(err)
+		rollback(journal)
+		return false
+	}
+	newLocalVer, _, _, err := goup.LocalGoInfo(goExeFullPath)
+	if err != nil || newLocalVer != latestVer {
+		printVerbose("Error: %v\n", err)
+		fmt.Println("New Go cannot be verified, restoring...")
+		rollback(journal)
+		return false
+	}
+	journal.Complete()
+	return true
+}
+
+// preflight checks permissions and free space before downloading. Size of new
+// installation is estimated from the archive, as a Go release unpacks to
+// about 4 times of it, or from the current installation.
+func preflight(root, tempDir, backupDir string, ver goup.VersionInfo, goos, arch string, stream bool, backupSize int64) error {
+	archiveSize, err := goup.RemoteSize(goup.DownloadUrl(ver, goos, arch))
+	if err != nil || archiveSize < 0 {
+		printVerbose("Cannot determine download size: %v\n", err)
+		archiveSize = 0
+	}
+	currentSize, _ := goup.DirSize(root)
+	check := goup.PreflightCheck{GoRoot: root, TempDir: tempDir, InstallSize: archiveSize * 4, BackupSize: backupSize, BackupDir: backupDir}
+	if check.InstallSize == 0 {
+		check.InstallSize = currentSize
+	}
+	if !stream {
+		// Current installation is removed before extracting
+		check.DownloadSize = archiveSize
+		check.InstallSize -= currentSize
+	}
+	printVerbose("Preflight: download %s, installation %s, backup %s\n",
+		goup.FormatBytes(check.DownloadSize), goup.FormatBytes(check.InstallSize), goup.FormatBytes(check.BackupSize))
+	return goup.Preflight(check)
+}
+
+// newBackupPath creates the backup directory, or the file for compressed
+// backup, in temporary directory. Hardlinked backup is created next to gopath
+// instead, as links cannot cross file systems.
+func newBackupPath(gopath string, localVer goup.VersionInfo, opts backupOptions) (string, error) {
+	prefix := "gobackup-" + localVer.String() + "-"
+	if opts.format == "dir" {
+		if opts.strategy == "hardlink" {
+			return ioutil.TempDir(filepath.Dir(filepath.Clean(gopath)), "."+prefix)
+		}
+		return ioutil.TempDir("", prefix)
+	}
+	f, err := ioutil.TempFile("", prefix+"*"+goup.BackupArchiveExt)
+	if err != nil {
+		return "", err
+	}
+	f.Close()
+	return f.Name(), nil
+}
+
+// rollback restores the Go installation to the state before upgrade. The
+// journal is kept if it fails so it can be retried in next run.
+func rollback(journal *goup.Journal) {
+	if err := journal.Recover(); err != nil {
+		fmt.Println("Unrecoverable error, please consider reinstall Go manually ", err)
+	}
+}
+
+func maxChannel(a, b goup.Channel) goup.Channel {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func printVerbose(format string, a ...interface{}) {
+	if *verbose {
+		fmt.Printf(format, a...)
+	}
+}
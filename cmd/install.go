@@ -0,0 +1,262 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/mkishere/goup"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+	pb "gopkg.in/cheggaaa/pb.v1"
+)
+
+var (
+	downloadCmd  = kingpin.Command("download", "Download and verify Go release archive for any platform.")
+	downloadVer  = downloadCmd.Arg("version", "Go version to download, or latest.").Required().String()
+	downloadOS   = downloadCmd.Flag("os", "Target operating system.").Default(runtime.GOOS).String()
+	downloadArch = downloadCmd.Flag("arch", "Target architecture.").Default(runtime.GOARCH).String()
+	downloadOut  = downloadCmd.Flag("out", "Directory to save the archive.").Default(".").String()
+
+	installCmd    = kingpin.Command("install", "Download, verify and unpack Go release for any platform without running it.")
+	installVer    = installCmd.Arg("version", "Go version to install, or latest.").Required().String()
+	installOS     = installCmd.Flag("os", "Target operating system.").Default(runtime.GOOS).String()
+	installArch   = installCmd.Flag("arch", "Target architecture.").Default(runtime.GOARCH).String()
+	installPrefix = installCmd.Flag("prefix", "Directory to install Go into. Defaults to a directory under $GOUP_HOME/versions.").String()
+	installNoPre  = installCmd.Flag("no-preflight", "Skip checking permissions and free space before downloading.").Bool()
+	installStream = installCmd.Flag("stream", "Extract tar.gz archive while downloading instead of saving it first.").Bool()
+	installSource = installCmd.Flag("from-source", "Build Go from source archive with make.bash, for platforms without binary release.").Bool()
+	installBoot   = installCmd.Flag("bootstrap", "GOROOT of Go building from source. Defaults to the newest suitable Go installed by goup or on $PATH.").String()
+	installExp    = installCmd.Flag("goexperiment", "GOEXPERIMENT for building from source.").String()
+	installCgo    = installCmd.Flag("cgo", "CGO_ENABLED for building from source: 0 or 1. Detected by make.bash if omitted.").Enum("0", "1")
+)
+
+func download() {
+	ver, err := releaseArg(*downloadVer)
+	if err != nil {
+		fmt.Println("Cannot determine version to download:", err)
+		os.Exit(1)
+	}
+	archive, err := downloadRelease(ver, *downloadOS, *downloadArch, *downloadOut)
+	if err != nil {
+		fmt.Println("Cannot download Go:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Saved go%v for %s/%s to %s\n", ver, *downloadOS, *downloadArch, archive)
+}
+
+func install() {
+	ver, err := releaseArg(*installVer)
+	if err != nil {
+		fmt.Println("Cannot determine version to install:", err)
+		os.Exit(1)
+	}
+	root := *installPrefix
+	if root == "" {
+		root = goup.ManagedRoot(ver, *installOS, *installArch)
+	}
+	if root, err = filepath.Abs(root); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if err := os.MkdirAll(goup.DownloadsDir(), 0755); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if *installSource {
+		installFromSource(ver, root)
+		return
+	}
+	if !*installNoPre {
+		if err := preflight(root, goup.DownloadsDir(), "", ver, *installOS, *installArch, *installStream, 0); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(root), 0755); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	lock, err := goup.LockInstallRoot(root, false, nil)
+	if err != nil {
+		fmt.Printf("Cannot lock %s: %v\n", root, err)
+		os.Exit(1)
+	}
+	defer lock.Unlock()
+
+	if *installStream {
+		fmt.Printf("Downloading from %s and installing to %s\n", goup.DownloadUrl(ver, *installOS, *installArch), root)
+		progress, finish := progressBar()
+		err = goup.StreamInstall(ver, *installOS, *installArch, root, progress, printVerbose)
+		finish()
+	} else {
+		var archive string
+		archive, err = downloadRelease(ver, *installOS, *installArch, goup.DownloadsDir())
+		if err == nil {
+			fmt.Printf("Installing go%v for %s/%s to %s\n", ver, *installOS, *installArch, root)
+			err = goup.InstallArchive(archive, root, ver, *installOS, *installArch, printVerbose)
+		}
+	}
+	if err != nil {
+		fmt.Println("Cannot install Go:", err)
+		lock.Unlock()
+		os.Exit(1)
+	}
+}
+
+// installFromSource builds Go from source archive and installs it to root
+func installFromSource(ver goup.VersionInfo, root string) {
+	if *installOS != runtime.GOOS || *installArch != runtime.GOARCH {
+		fmt.Printf("Building from source is only supported for the host platform %s/%s\n", runtime.GOOS, runtime.GOARCH)
+		os.Exit(1)
+	}
+	if *installStream {
+		fmt.Println("--stream cannot be used with --from-source")
+		os.Exit(1)
+	}
+	bootstrap, err := bootstrapRoot(ver)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if !*installNoPre {
+		if err := preflightSource(root, ver); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(root), 0755); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	lock, err := goup.LockInstallRoot(root, false, nil)
+	if err != nil {
+		fmt.Printf("Cannot lock %s: %v\n", root, err)
+		os.Exit(1)
+	}
+	defer lock.Unlock()
+
+	fmt.Printf("Downloading from %s\n", goup.SourceUrl(ver))
+	progress, finish := progressBar()
+	archive, err := goup.DownloadSource(ver, goup.DownloadsDir(), progress)
+	finish()
+	if err == nil {
+		fmt.Println("Checksum verified")
+		fmt.Printf("Building go%v with Go in %s and installing to %s\n", ver, bootstrap, root)
+		opts := goup.SourceBuildOptions{Bootstrap: bootstrap, GOEXPERIMENT: *installExp, CGOEnabled: *installCgo, Output: os.Stdout}
+		err = goup.BuildFromSource(archive, root, ver, opts, printVerbose)
+	}
+	if err != nil {
+		fmt.Println("Cannot install Go:", err)
+		lock.Unlock()
+		os.Exit(1)
+	}
+}
+
+// preflightSource checks permissions and free space before building Go from
+// source. While building, the tree with its build cache takes about 16 times
+// of the source archive.
+func preflightSource(root string, ver goup.VersionInfo) error {
+	archiveSize, err := goup.RemoteSize(goup.SourceUrl(ver))
+	if err != nil || archiveSize < 0 {
+		printVerbose("Cannot determine download size: %v\n", err)
+		archiveSize = 0
+	}
+	check := goup.PreflightCheck{GoRoot: root, TempDir: goup.DownloadsDir(), DownloadSize: archiveSize, InstallSize: archiveSize * 16}
+	printVerbose("Preflight: download %s, build %s\n", goup.FormatBytes(check.DownloadSize), goup.FormatBytes(check.InstallSize))
+	return goup.Preflight(check)
+}
+
+// bootstrapRoot returns GOROOT of Go building ver from source, given by
+// --bootstrap or selected from Go installed by goup and go on PATH
+func bootstrapRoot(ver goup.VersionInfo) (string, error) {
+	if *installBoot != "" {
+		root, err := filepath.Abs(*installBoot)
+		if err != nil {
+			return "", err
+		}
+		return root, goup.CheckBootstrap(ver, root)
+	}
+	candidates, err := goup.ManagedInstalls()
+	if err != nil {
+		return "", err
+	}
+	if goExe, localVer, platform, arch, err := localGo(""); err == nil {
+		if env, err := goup.ReadGoEnv(goExe); err == nil {
+			candidates = append(candidates, goup.ManagedInstall{Version: localVer, GOOS: platform, Arch: arch, Root: env.GOROOT})
+		}
+	}
+	bootstrap, err := goup.SelectBootstrap(ver, candidates)
+	if err != nil {
+		return "", err
+	}
+	return bootstrap.Root, nil
+}
+
+// releaseArg parses version given in command line, where latest means the
+// latest stable release
+func releaseArg(arg string) (goup.VersionInfo, error) {
+	if arg == "latest" {
+		arg = ""
+	}
+	return versionArg(arg, func() (goup.VersionInfo, error) {
+		availVerList, err := goup.LatestVersionInfo()
+		if err != nil {
+			return goup.VersionInfo{}, err
+		}
+		return goup.SelectVersion(goup.VersionInfo{}, availVerList, goup.Policy{Kind: goup.PolicyMinor})
+	})
+}
+
+// downloadRelease downloads release archive into dir showing a progress bar
+func downloadRelease(ver goup.VersionInfo, goos, arch, dir string) (string, error) {
+	fmt.Printf("Downloading from %s\n", goup.DownloadUrl(ver, goos, arch))
+	progress, finish := progressBar()
+	archive, err := goup.DownloadRelease(ver, goos, arch, dir, progress)
+	finish()
+	if err == nil {
+		fmt.Println("Checksum verified")
+	}
+	return archive, err
+}
+
+// streamRelease downloads and extracts release archive into a staging
+// directory next to root, returning the staging directory
+func streamRelease(ver goup.VersionInfo, goos, arch, root string) (string, error) {
+	url := goup.DownloadUrl(ver, goos, arch)
+	sum, err := goup.FetchChecksum(url)
+	if err != nil {
+		return "", err
+	}
+	staging, err := goup.NewStagingDir(root)
+	if err != nil {
+		return "", err
+	}
+	fmt.Printf("Downloading from %s and extracting to %s\n", url, staging)
+	progress, finish := progressBar()
+	err = goup.StreamExtract(url, sum, staging, progress, printVerbose)
+	finish()
+	if err != nil {
+		os.RemoveAll(staging)
+		return "", err
+	}
+	fmt.Println("Checksum verified")
+	return staging, nil
+}
+
+// progressBar returns a download progress callback showing a progress bar in
+// console, and a function to call after download
+func progressBar() (func(totalSize int64, src io.Reader) io.Reader, func()) {
+	var bar *pb.ProgressBar
+	return func(totalSize int64, src io.Reader) io.Reader {
+			bar = pb.New(int(totalSize)).SetUnits(pb.U_BYTES)
+			bar.Start()
+			return bar.NewProxyReader(src)
+		}, func() {
+			if bar != nil {
+				bar.Finish()
+			}
+		}
+}
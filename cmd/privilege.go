@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+
+	"github.com/mkishere/goup"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	applyCmd        = kingpin.Command("apply", "Replace Go installation with a downloaded archive. Run by goup through sudo or doas.").Hidden()
+	applyRoot       = applyCmd.Arg("goroot", "Go installation to replace.").Required().String()
+	applyArchive    = applyCmd.Flag("archive", "Release archive verified by unprivileged goup.").Required().String()
+	applySum        = applyCmd.Flag("sha256", "SHA-256 of the archive.").Required().String()
+	applyVer        = applyCmd.Flag("version", "Go version in the archive.").Required().String()
+	applyBackupFmt  = applyCmd.Flag("backup-format", "").Default("dir").Enum("dir", "tar.gz")
+	applyBackupStr  = applyCmd.Flag("backup-strategy", "").Default("auto").Enum("auto", "copy", "hardlink")
+	applyBackupJobs = applyCmd.Flag("backup-workers", "").Int()
+	applyWait       = applyCmd.Flag("wait", "").Bool()
+	applyJournal    = applyCmd.Flag("journal-dir", "Journal directory of the user running goup.").Required().String()
+)
+
+// choosePrivilege decides how to install when current user cannot modify
+// gopath, asking user unless --privilege is given
+func choosePrivilege(gopath string, latestVer goup.VersionInfo) string {
+	if *privMode != "ask" {
+		return *privMode
+	}
+	if *autoUpd {
+		return "fail"
+	}
+	elevator, err := goup.ElevateCommand()
+	fmt.Printf("%s cannot be modified by current user. You can:\n", gopath)
+	if err == nil {
+		fmt.Printf("  1) Download as current user and install with %s\n", filepath.Base(elevator))
+	}
+	fmt.Printf("  2) Install Go %v for current user only in %s\n", latestVer, goup.UserGoRoot())
+	fmt.Println("  3) Cancel")
+	for {
+		fmt.Print("Your choice: ")
+		// Nobody to answer, cancel
+		input, ok := readAnswer()
+		if !ok {
+			fmt.Println()
+			return "fail"
+		}
+		switch input {
+		case "1":
+			if err == nil {
+				return "sudo"
+			}
+		case "2":
+			return "user"
+		case "3":
+			return "fail"
+		}
+	}
+}
+
+// elevateUpgrade downloads and verifies the archive as current user, then
+// runs goup apply with sudo or doas to replace gopath
+func elevateUpgrade(gopath string, localVer, latestVer goup.VersionInfo, platform, arch string) {
+	elevator, err := goup.ElevateCommand()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	self, err := os.Executable()
+	if err != nil {
+		fmt.Println("Cannot locate goup executable:", err)
+		return
+	}
+	dir, err := ioutil.TempDir("", "goup-download-")
+	if err != nil {
+		fmt.Println("Cannot create temporary directory:", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	archive, err := downloadRelease(latestVer, platform, arch, dir)
+	if err != nil {
+		fmt.Println("Cannot download file:", err)
+		return
+	}
+	sum, err := goup.FileSHA256(archive)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Journal stays with current user, who recovers the upgrade next time
+	if err := os.MkdirAll(goup.JournalDir(), 0755); err != nil {
+		fmt.Println("Cannot create journal directory:", err)
+		return
+	}
+
+	args := []string{self, "apply", "--archive", archive, "--sha256", sum, "--version", latestVer.String(),
+		"--backup-format", *backupFmt, "--backup-strategy", *backupStr, "--backup-workers", strconv.Itoa(*backupJobs),
+		"--journal-dir", goup.JournalDir()}
+	if *autoRecover {
+		args = append(args, "--auto-recover")
+	}
+	if *waitLock {
+		args = append(args, "--wait")
+	}
+	if *verbose {
+		args = append(args, "--verbose")
+	}
+	args = append(args, gopath)
+	fmt.Printf("Installing with %s\n", filepath.Base(elevator))
+	printVerbose("Running %s %v\n", elevator, args)
+	cmd := exec.Command(elevator, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	err = cmd.Run()
+	recordUpgrade(gopath, localVer, latestVer, err == nil)
+	if err != nil {
+		fmt.Printf("Installing with %s failed: %v\n", filepath.Base(elevator), err)
+		os.Exit(1)
+	}
+	// Tools belong to current user, not the one installing
+	offerToolRebuild(filepath.Join(gopath, "bin", "go"))
+}
+
+// apply replaces Go installation with archive downloaded by an unprivileged
+// goup. The archive is copied and verified again as its owner could change
+// it in the meantime.
+func apply() {
+	gopath, err := filepath.Abs(*applyRoot)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	goExeFullPath := filepath.Join(gopath, "bin", "go")
+	localVer, _, _, err := goup.LocalGoInfo(goExeFullPath)
+	if err != nil {
+		fmt.Println("Error when getting local Go infomration", err)
+		os.Exit(1)
+	}
+	latestVer, err := versionArg(*applyVer, nil)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	archive, err := goup.CopyVerified(*applyArchive, *applySum)
+	if err != nil {
+		fmt.Println("Cannot verify archive:", err)
+		os.Exit(1)
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
+	lock, err := goup.LockInstallRoot(gopath, *applyWait, nil)
+	if err != nil {
+		fmt.Println("Cannot lock Go installation directory:", err)
+		os.Exit(1)
+	}
+	ok := applyUpgrade(gopath, goExeFullPath, localVer, latestVer, archive, -1, "", backupOptions{*applyBackupFmt, *applyBackupStr, *applyBackupJobs})
+	lock.Unlock()
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+// userInstall installs Go into per-user root and prints how to use it
+func userInstall(latestVer goup.VersionInfo, platform, arch string) {
+	root := goup.UserGoRoot()
+	if _, err := os.Stat(root); err == nil {
+		fmt.Printf("%s already exists, upgrade it with \"goup upgrade %s\"\n", root, filepath.Join(root, "bin"))
+		printPathHint(filepath.Join(root, "bin"))
+		return
+	}
+	if err := os.MkdirAll(goup.DownloadsDir(), 0755); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if !*noPreflgt {
+		if err := preflight(root, goup.DownloadsDir(), "", latestVer, platform, arch, false, 0); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+	lock, err := goup.LockInstallRoot(root, false, nil)
+	if err != nil {
+		fmt.Printf("Cannot lock %s: %v\n", root, err)
+		return
+	}
+	defer lock.Unlock()
+
+	archive, err := downloadRelease(latestVer, platform, arch, goup.DownloadsDir())
+	if err != nil {
+		fmt.Println("Cannot download file:", err)
+		return
+	}
+	fmt.Printf("Installing Go %v to %s\n", latestVer, root)
+	if err := goup.InstallArchive(archive, root, latestVer, platform, arch, printVerbose); err != nil {
+		fmt.Println("Cannot install Go:", err)
+		return
+	}
+	printPathHint(filepath.Join(root, "bin"))
+}
+
+// printPathHint shows how to put bin before other Go on PATH
+func printPathHint(bin string) {
+	fmt.Println("To use it, put it before other Go on your PATH:")
+	if runtime.GOOS == "windows" {
+		fmt.Printf("  setx PATH \"%s;%%PATH%%\"\n", bin)
+		return
+	}
+	fmt.Printf("  export PATH=\"%s:$PATH\"\n", bin)
+	fmt.Println("Add the line to your shell profile (e.g. ~/.profile) to keep it, and unset GOROOT if it is set.")
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mkishere/goup"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+var autoRecover = kingpin.Flag("auto-recover", "Recover interrupted upgrades without confirmation.").Bool()
+
+// recoverPendingUpgrades looks for upgrades interrupted before completion
+// and offers to restore the installation to the last consistent state. If
+// root is given, only upgrades of that installation are recovered.
+func recoverPendingUpgrades(root string) {
+	journals, err := goup.PendingJournals()
+	if err != nil {
+		fmt.Println("Cannot read upgrade journal:", err)
+		return
+	}
+	for _, j := range journals {
+		if root != "" && !samePath(j.TargetPath, root) {
+			continue
+		}
+		// Upgrade still running in another goup
+		lock, err := goup.LockInstallRoot(j.TargetPath, false, nil)
+		if err != nil {
+			printVerbose("Skip recovering %s: %v\n", j.TargetPath, err)
+			continue
+		}
+
+		fmt.Fprintf(os.Stderr, "Upgrade of %s from %v to %v was interrupted at %s phase (started %s)\n",
+			j.TargetPath, j.FromVersion, j.ToVersion, j.Phase, j.StartedAt.Format("2006-01-02 15:04:05"))
+		if *autoRecover || confirm("Do you want to recover it now (Y/n):") {
+			printVerbose("Restoring from %s\n", j.BackupPath)
+			if err := j.Recover(); err != nil {
+				fmt.Println("Recovery failed, please consider reinstall Go manually:", err)
+			} else {
+				fmt.Printf("%s is recovered to Go %v\n", j.TargetPath, j.FromVersion)
+			}
+		}
+		lock.Unlock()
+	}
+}
+
+// confirm asks user a yes/no question on stderr until Y or N is answered. It
+// is no when stdin ends without an answer.
+func confirm(prompt string) bool {
+	for {
+		fmt.Fprint(os.Stderr, prompt)
+		input, ok := readAnswer()
+		if !ok {
+			fmt.Fprintln(os.Stderr)
+			return false
+		}
+		switch input {
+		case "Y", "y":
+			return true
+		case "n", "N":
+			return false
+		}
+	}
+}
+
+// samePath tells if a and b are the same path once made absolute
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
+
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// readAnswer reads a line from stdin, which may be piped. ok is false if
+// stdin is closed.
+func readAnswer() (answer string, ok bool) {
+	line, err := stdinReader.ReadString('\n')
+	if err == io.EOF && line == "" || err != nil && err != io.EOF {
+		return "", false
+	}
+	return strings.TrimSpace(line), true
+}
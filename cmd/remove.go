@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/mkishere/goup"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	removeCmd   = kingpin.Command("remove", "Remove Go versions installed by goup install, with their downloads and backups.")
+	removeVers  = removeCmd.Arg("version", "Versions or constraints to remove, e.g. 1.21.5 or 1.20.").Strings()
+	removeAll   = removeCmd.Flag("all", "Remove every version installed by goup.").Bool()
+	removeForce = removeCmd.Flag("force", "Remove versions in use too, without confirmation.").Bool()
+	removeOS    = removeCmd.Flag("os", "Target OS of versions to remove.").Default(runtime.GOOS).String()
+	removeArch  = removeCmd.Flag("arch", "Target architecture of versions to remove.").Default(runtime.GOARCH).String()
+)
+
+func remove() {
+	if *removeAll == (len(*removeVers) > 0) {
+		fmt.Println("Give versions to remove, or --all")
+		os.Exit(1)
+	}
+	installs, err := goup.ManagedInstalls()
+	if err != nil {
+		fmt.Println("Cannot list installed versions:", err)
+		os.Exit(1)
+	}
+	selected, err := selectInstalls(installs)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if len(selected) == 0 {
+		fmt.Println("No installed version matches")
+		os.Exit(1)
+	}
+	if *removeAll && !*removeForce && !confirm(fmt.Sprintf("Remove %d Go versions (Y/n):", len(selected))) {
+		fmt.Println("Nothing is removed")
+		os.Exit(1)
+	}
+
+	ok := true
+	var freed int64
+	removed := make([]goup.VersionInfo, 0, len(selected))
+	for _, inst := range selected {
+		if reason := activeReason(inst); reason != "" && !*removeForce {
+			fmt.Printf("Go %v is in use (%s), use --force to remove it anyway\n", inst.Version, reason)
+			ok = false
+			continue
+		}
+		size, err := goup.RemoveInstall(inst)
+		if err != nil {
+			fmt.Printf("Cannot remove Go %v: %v\n", inst.Version, err)
+			ok = false
+			continue
+		}
+		freed += size
+		removed = append(removed, inst.Version)
+		fmt.Printf("Removed Go %v %s/%s from %s\n", inst.Version, inst.GOOS, inst.Arch, inst.Root)
+
+		artifacts, err := goup.VersionArtifacts(inst)
+		if err != nil {
+			printVerbose("Cannot look for downloads and backups: %v\n", err)
+		}
+		for _, item := range artifacts {
+			if err := item.Remove(); err != nil {
+				fmt.Printf("Cannot remove %s: %v\n", item.Path, err)
+				continue
+			}
+			printVerbose("Removed %s %s\n", item.Kind, item.Path)
+			freed += item.Size
+		}
+	}
+	updateDefaultVersion(removed)
+	fmt.Printf("%s freed\n", goup.FormatBytes(freed))
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+// selectInstalls picks installs for --os and --arch matching versions given
+// on command line, or all with --all
+func selectInstalls(installs []goup.ManagedInstall) ([]goup.ManagedInstall, error) {
+	constraints := make([]goup.Constraint, 0, len(*removeVers))
+	for _, spec := range *removeVers {
+		c, err := goup.ParseConstraint(strings.TrimPrefix(spec, "go"))
+		if err != nil {
+			return nil, err
+		}
+		constraints = append(constraints, c)
+	}
+	selected := make([]goup.ManagedInstall, 0)
+	for _, inst := range installs {
+		if *removeAll {
+			selected = append(selected, inst)
+			continue
+		}
+		if inst.GOOS != *removeOS || inst.Arch != *removeArch {
+			continue
+		}
+		for _, c := range constraints {
+			if c.Match(inst.Version) {
+				selected = append(selected, inst)
+				break
+			}
+		}
+	}
+	return selected, nil
+}
+
+// activeReason tells why inst is in use: shims or goup env select it for
+// current directory, or its go is first on PATH. Returns "" if it is not.
+func activeReason(inst goup.ManagedInstall) string {
+	if inst.GOOS != runtime.GOOS || inst.Arch != runtime.GOARCH {
+		return ""
+	}
+	if res, err := goup.Resolve("."); err == nil && res.GoRoot == inst.Root {
+		return fmt.Sprintf("selected by %s", res.Request.Source)
+	}
+	if exes := goup.FindGoOnPath(os.Getenv("PATH")); len(exes) > 0 {
+		exe, err := filepath.EvalSymlinks(exes[0])
+		root, rootErr := filepath.EvalSymlinks(inst.Root)
+		if err == nil && rootErr == nil && filepath.Dir(filepath.Dir(exe)) == root {
+			return fmt.Sprintf("%s is first go on PATH", exes[0])
+		}
+	}
+	return ""
+}
+
+// updateDefaultVersion points global default to the newest installed version
+// if the version it selects is among removed ones and no other satisfies it,
+// or clears it if none is left
+func updateDefaultVersion(removed []goup.VersionInfo) {
+	spec, err := goup.DefaultVersion()
+	if err != nil || spec == "" {
+		return
+	}
+	c, err := goup.ParseConstraint(spec)
+	if err != nil {
+		return
+	}
+	if _, ok, _ := goup.NewestInstalled(c); ok {
+		return
+	}
+	selectedRemoved := false
+	for _, v := range removed {
+		selectedRemoved = selectedRemoved || c.Match(v)
+	}
+	if !selectedRemoved {
+		return
+	}
+	installed, _ := goup.InstalledVersions()
+	if len(installed) == 0 {
+		if err := goup.ClearDefaultVersion(); err == nil {
+			fmt.Printf("Default version %s is cleared as no version is left\n", spec)
+		}
+		return
+	}
+	if err := goup.SetDefaultVersion(installed[0].String()); err == nil {
+		fmt.Printf("Default version is changed from %s to %v\n", spec, installed[0])
+	}
+}
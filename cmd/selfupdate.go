@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/mkishere/goup"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+// version of goup, set at build time with -ldflags "-X main.version=1.2.0"
+var version = "devel"
+
+var (
+	versionCmd = kingpin.Command("version", "Print goup version.")
+
+	selfUpdateCmd   = kingpin.Command("self-update", "Update goup itself from a release index.")
+	selfUpdateIndex = selfUpdateCmd.Flag("index", "URL or path of goup release index (JSON).").Envar(goup.SelfUpdateIndexEnv).String()
+	selfUpdateCheck = selfUpdateCmd.Flag("check", "Only tell if a newer goup is available.").Bool()
+	selfUpdateForce = selfUpdateCmd.Flag("force", "Install the latest release even if it is not newer, e.g. over a development build.").Bool()
+)
+
+func selfUpdate() {
+	if *selfUpdateIndex == "" {
+		fmt.Printf("No release index is configured. Use --index or set %s\n", goup.SelfUpdateIndexEnv)
+		os.Exit(1)
+	}
+	exe, err := os.Executable()
+	if err == nil {
+		exe, err = filepath.EvalSymlinks(exe)
+	}
+	if err != nil {
+		fmt.Println("Cannot locate goup executable:", err)
+		os.Exit(1)
+	}
+	goup.RemoveOldExecutable(exe)
+
+	index, err := goup.FetchSelfIndex(*selfUpdateIndex)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	latest, file, ok := index.Latest(runtime.GOOS, runtime.GOARCH)
+	if !ok {
+		fmt.Printf("No goup release for %s/%s in %s\n", runtime.GOOS, runtime.GOARCH, *selfUpdateIndex)
+		os.Exit(1)
+	}
+	latestVer, _ := goup.ParseSelfVersion(latest.Version)
+	currentVer, err := goup.ParseSelfVersion(version)
+	switch {
+	case err != nil && !*selfUpdateForce:
+		fmt.Printf("goup %s is a development build, latest release is %s. Use --force to replace it\n", version, latest.Version)
+		return
+	case err == nil && goup.CompareVersion(latestVer, currentVer) <= 0 && !*selfUpdateForce:
+		fmt.Printf("goup %s is up to date\n", version)
+		return
+	}
+	if *selfUpdateCheck {
+		fmt.Printf("goup %s is available, current version is %s\n", latest.Version, version)
+		return
+	}
+	fmt.Printf("Downloading goup %s\n", latest.Version)
+	// Download next to executable so it can be renamed over it
+	newExe, err := goup.DownloadSelfRelease(*selfUpdateIndex, file, filepath.Dir(exe))
+	if os.IsPermission(err) {
+		fmt.Printf("Cannot replace %s as current user, run goup self-update with sudo\n", exe)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if out, err := exec.Command(newExe, "version").CombinedOutput(); err != nil {
+		fmt.Printf("Downloaded goup does not run: %v %s\n", err, strings.TrimSpace(string(out)))
+		os.Remove(newExe)
+		os.Exit(1)
+	}
+	if err := goup.ReplaceExecutable(exe, newExe); err != nil {
+		fmt.Printf("Cannot replace %s: %v\n", exe, err)
+		os.Remove(newExe)
+		os.Exit(1)
+	}
+	fmt.Printf("goup is updated from %s to %s\n", version, latest.Version)
+	updateShimCopies(exe)
+}
+
+// updateShimCopies refreshes shims on Windows, which are copies of goup
+// rather than links to it and would keep running the old version
+func updateShimCopies(exe string) {
+	if runtime.GOOS != "windows" {
+		return
+	}
+	if _, err := os.Stat(filepath.Join(goup.ShimsDir(), "go.exe")); err != nil {
+		return
+	}
+	if err := goup.InstallShims(exe, goup.ShimsDir()); err != nil {
+		fmt.Printf("Cannot update shims, run goup shim install when no go command is running: %v\n", err)
+		return
+	}
+	fmt.Printf("Shims in %s are updated\n", goup.ShimsDir())
+}
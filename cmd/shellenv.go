@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/mkishere/goup"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	envCmd   = kingpin.Command("env", "Print shell commands putting Go version selected for current directory on PATH.")
+	envVer   = envCmd.Arg("version", "Installed version to use instead of the one selected for current directory.").String()
+	envShell = envCmd.Flag("shell", "Shell to print commands for: bash, zsh, fish or powershell. Detected from $SHELL if omitted.").Enum(goup.Shells...)
+	envHook  = envCmd.Flag("hook", "Do not fail if no version is selected, used by shell hook.").Hidden().Bool()
+
+	initCmd   = kingpin.Command("init", "Print shell hook updating PATH on directory change, evaluated by eval \"$(goup init -)\".")
+	initPrint = initCmd.Flag("print", "Print the hook itself instead of setup instructions, same as goup init -.").Hidden().Bool()
+	initShell = initCmd.Flag("shell", "Shell to print hook for: bash, zsh, fish or powershell. Detected from $SHELL if omitted.").Enum(goup.Shells...)
+
+	execCmd  = kingpin.Command("exec", "Run a command with an installed Go version first on PATH, e.g. goup exec 1.21 -- go test ./...")
+	execVer  = execCmd.Arg("version", "Installed version or constraint, e.g. 1.21.5 or 1.21.").Required().String()
+	execArgs = execCmd.Arg("command", "Command and its arguments.").Required().Strings()
+)
+
+// installedRoot returns GOROOT of the newest installed version satisfying
+// spec given on command line
+func installedRoot(spec string) (string, error) {
+	spec = strings.TrimPrefix(spec, "go")
+	c, err := goup.ParseConstraint(spec)
+	if err != nil {
+		return "", err
+	}
+	v, ok, err := goup.NewestInstalled(c)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", &goup.NotInstalledError{Request: goup.VersionRequest{Constraint: c, Source: "command line", Reason: "argument"}}
+	}
+	return goup.ManagedRoot(v, runtime.GOOS, runtime.GOARCH), nil
+}
+
+func shellEnv() {
+	shell := *envShell
+	if shell == "" {
+		shell = goup.DetectShell()
+	}
+	var goroot string
+	var err error
+	if *envVer != "" {
+		goroot, err = installedRoot(*envVer)
+	} else {
+		var res goup.Resolution
+		res, err = goup.Resolve(".")
+		goroot = res.GoRoot
+	}
+	if err != nil {
+		// The hook removes managed versions from PATH instead
+		if !*envHook {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if err != goup.ErrNoVersionRequested {
+			fmt.Fprintln(os.Stderr, "goup:", err)
+		}
+		goroot = ""
+	}
+	script, err := goup.ShellEnv(shell, goup.ActivateEnv(goroot, os.Getenv))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Print(script)
+}
+
+// initDashArgs rewrites the conventional "goup init -", which kingpin cannot
+// parse, to "goup init --print"
+func initDashArgs(args []string) []string {
+	if len(args) < 2 || args[1] != "init" {
+		return args
+	}
+	rewritten := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "-" {
+			arg = "--print"
+		}
+		rewritten[i] = arg
+	}
+	return rewritten
+}
+
+func initHook() {
+	shell := *initShell
+	if shell == "" {
+		shell = goup.DetectShell()
+	}
+	if !*initPrint {
+		switch shell {
+		case "bash", "zsh":
+			fmt.Printf("Add this line to ~/.%src to select Go version when changing directory:\n", shell)
+			fmt.Println("  eval \"$(goup init -)\"")
+		case "fish":
+			fmt.Println("Add this line to ~/.config/fish/config.fish to select Go version when changing directory:")
+			fmt.Println("  goup init - --shell fish | source")
+		case "powershell":
+			fmt.Println("Add this line to your $PROFILE to select Go version when changing directory:")
+			fmt.Println("  goup init - --shell powershell | Out-String | Invoke-Expression")
+		}
+		return
+	}
+	self, err := os.Executable()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Cannot locate goup executable:", err)
+		os.Exit(1)
+	}
+	script, err := goup.ShellHook(shell, self)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Print(script)
+}
+
+// execVersion runs command with the requested Go on PATH and GOROOT set.
+// GOTOOLCHAIN=local keeps go from switching to a toolchain of go.mod.
+func execVersion() {
+	goroot, err := installedRoot(*execVer)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	for _, v := range goup.ActivateEnv(goroot, os.Getenv) {
+		os.Setenv(v.Name, v.Value)
+	}
+	os.Setenv("GOTOOLCHAIN", "local")
+	args := *execArgs
+	exe, err := exec.LookPath(args[0])
+	if err == nil {
+		exe, err = filepath.Abs(exe)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := execTool(exe, args, os.Environ()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/mkishere/goup"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	shimCmd        = kingpin.Command("shim", "Manage go and gofmt shims selecting Go version per directory.")
+	shimInstallCmd = shimCmd.Command("install", "Create shims in $GOUP_HOME/shims, to be put first on PATH.")
+
+	whichCmd  = kingpin.Command("which", "Show which Go version shims use in a directory and why.")
+	whichDir  = whichCmd.Arg("dir", "Directory to check. Defaults to current directory.").Default(".").String()
+	whichTool = whichCmd.Flag("tool", "Print only the path of the tool (go or gofmt) shims would run.").Enum(goup.ShimTools...)
+
+	defaultCmd = kingpin.Command("default", "Show or set the global default Go version used by shims.")
+	defaultVer = defaultCmd.Arg("version", "Version or constraint to set, e.g. 1.22.1 or 1.22.").String()
+)
+
+// runShim runs tool of the Go version resolved for current directory,
+// passing through arguments. It is called when goup is invoked through a
+// shim.
+func runShim(tool string) {
+	dir, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "goup:", err)
+		os.Exit(1)
+	}
+	res, err := goup.Resolve(dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "goup:", err)
+		os.Exit(1)
+	}
+	exe := filepath.Join(res.GoRoot, "bin", tool)
+	if runtime.GOOS == "windows" {
+		exe += ".exe"
+	}
+	env := append(os.Environ(), "GOROOT="+res.GoRoot)
+	if err := execTool(exe, append([]string{tool}, os.Args[1:]...), env); err != nil {
+		fmt.Fprintln(os.Stderr, "goup:", err)
+		os.Exit(1)
+	}
+}
+
+func shimInstall() {
+	self, err := os.Executable()
+	if err == nil {
+		self, err = filepath.EvalSymlinks(self)
+	}
+	if err != nil {
+		fmt.Println("Cannot locate goup executable:", err)
+		os.Exit(1)
+	}
+	if err := goup.InstallShims(self, goup.ShimsDir()); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Printf("Shims created in %s\n", goup.ShimsDir())
+	printPathHint(goup.ShimsDir())
+	if _, err := goup.RequestedVersion("."); err == goup.ErrNoVersionRequested {
+		fmt.Println("Set the version used outside projects with \"goup default <version>\"")
+	}
+}
+
+func which() {
+	res, err := goup.Resolve(*whichDir)
+	if *whichTool != "" {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Println(filepath.Join(res.GoRoot, "bin", *whichTool))
+		return
+	}
+	if err == goup.ErrNoVersionRequested {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if _, ok := err.(*goup.NotInstalledError); err != nil && !ok {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Printf("Requested: %v\n", res.Request.Constraint)
+	fmt.Printf("Source:    %s (%s)\n", res.Request.Source, res.Request.Reason)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Printf("Selected:  go%v\n", res.Version)
+	fmt.Printf("GOROOT:    %s\n", res.GoRoot)
+}
+
+func defaultVersion() {
+	if *defaultVer == "" {
+		v, err := goup.DefaultVersion()
+		switch {
+		case err != nil:
+			fmt.Println(err)
+			os.Exit(1)
+		case v == "":
+			fmt.Println("No global default version")
+		default:
+			fmt.Println(v)
+		}
+		return
+	}
+	spec := strings.TrimPrefix(*defaultVer, "go")
+	if err := goup.SetDefaultVersion(spec); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	c, _ := goup.ParseConstraint(spec)
+	if _, ok, _ := goup.NewestInstalled(c); ok {
+		return
+	}
+	fmt.Printf("Go %s is not installed yet, install it with \"goup install %s\"\n", spec, spec)
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mkishere/goup"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	statusCmd      = kingpin.Command("status", "Show Go version and update status, or export them as Prometheus metrics.")
+	statusGo       = statusCmd.Arg("path", "Path to Go executable. Defaults to go on $PATH.").String()
+	statusPolicy   = statusCmd.Flag("policy", "Upgrade policy deciding whether an update is available, as for goup upgrade.").Default("patch").String()
+	statusVulnDB   = statusCmd.Flag("vulndb", "Go vulnerability database URL, directory or zip file, used by security policy.").Default(goup.DefaultVulnDB).String()
+	statusMetrics  = statusCmd.Flag("metrics", "Print Prometheus metrics instead.").Bool()
+	statusTextfile = statusCmd.Flag("textfile", "Write Prometheus metrics to file for node_exporter textfile collector.").String()
+	statusServe    = statusCmd.Flag("serve", "Serve /metrics and /status (JSON) on address, e.g. :9185.").String()
+	statusRefresh  = statusCmd.Flag("refresh", "How long version list and vulnerability database fetched by --serve are reused.").Default("1h").Duration()
+)
+
+// versionCache keeps version list and advisories fetched from remote for
+// --refresh
+type versionCache struct {
+	sync.Mutex
+	versions  []goup.VersionInfo
+	fetchedAt time.Time
+	entries   []goup.OSVEntry
+	loadedAt  time.Time
+}
+
+func (c *versionCache) get() ([]goup.VersionInfo, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.versions != nil && time.Since(c.fetchedAt) < *statusRefresh {
+		return c.versions, nil
+	}
+	versions, err := goup.LatestVersionInfo()
+	if err != nil {
+		// Stale list is better than none
+		return c.versions, err
+	}
+	c.versions, c.fetchedAt = versions, time.Now()
+	return versions, nil
+}
+
+func (c *versionCache) advisories() ([]goup.OSVEntry, error) {
+	c.Lock()
+	defer c.Unlock()
+	if !c.loadedAt.IsZero() && time.Since(c.loadedAt) < *statusRefresh {
+		return c.entries, nil
+	}
+	entries, err := goup.LoadVulnDB(*statusVulnDB)
+	if err != nil && !c.loadedAt.IsZero() {
+		// Stale advisories are better than none
+		printVerbose("Cannot reload vulnerability database: %v\n", err)
+		return c.entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.entries, c.loadedAt = entries, time.Now()
+	return entries, nil
+}
+
+// collectStatus gathers status of local Go. Versions are left unknown if the
+// version list cannot be fetched.
+func collectStatus(cache *versionCache) (goup.Status, error) {
+	goExeFullPath, localVer, platform, arch, err := localGo(*statusGo)
+	if err != nil {
+		return goup.Status{}, err
+	}
+	env, err := goup.ReadGoEnv(goExeFullPath)
+	if err != nil {
+		return goup.Status{}, err
+	}
+	policy, err := goup.ParsePolicy(*statusPolicy)
+	if err != nil {
+		return goup.Status{}, err
+	}
+	s := goup.Status{GoRoot: env.GOROOT, Local: localVer, GOOS: platform, Arch: arch, Policy: policy.String()}
+	if check, err := goup.LoadCheckResult(); err == nil {
+		s.LastCheck = &check
+	}
+	if upgrade, err := goup.LoadUpgradeRecord(); err == nil {
+		s.LastUpgrade = &upgrade
+	}
+
+	versions, err := cache.get()
+	if err != nil {
+		printVerbose("Cannot retrieve version information: %v\n", err)
+	}
+	if len(versions) == 0 {
+		// Fall back to the last check of this installation
+		if c := s.LastCheck; c != nil && c.Error == "" && c.GoRoot == s.GoRoot && c.Local == s.Local {
+			if p, err := goup.ParsePolicy(c.Policy); err == nil && p.String() == s.Policy {
+				s.Target = c.Latest
+			}
+		}
+		return s, nil
+	}
+	s.Latest, _ = goup.SelectVersion(localVer, versions, goup.Policy{Kind: goup.PolicyMinor})
+	if policy.Kind == goup.PolicySecurity {
+		entries, err := cache.advisories()
+		if err != nil {
+			printVerbose("Cannot load vulnerability database: %v\n", err)
+			return s, nil
+		}
+		policy.Advisories = goup.AffectingAdvisories(entries, localVer)
+	}
+	if localVer.RC {
+		policy.Channel = goup.ChannelRC
+	}
+	if localVer.Beta {
+		policy.Channel = goup.ChannelBeta
+	}
+	s.Target, _ = goup.SelectVersion(localVer, versions, policy)
+	return s, nil
+}
+
+func status() {
+	cache := &versionCache{}
+	if *statusServe != "" {
+		serveStatus(cache)
+		return
+	}
+	s, err := collectStatus(cache)
+	if err != nil {
+		fmt.Println("Error when getting local Go infomration", err)
+		os.Exit(1)
+	}
+	switch {
+	case *statusTextfile != "":
+		if err := goup.WriteMetricsFile(*statusTextfile, s); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	case *statusMetrics:
+		goup.WriteMetrics(os.Stdout, s)
+	default:
+		printStatus(s)
+	}
+}
+
+func printStatus(s goup.Status) {
+	var zero goup.VersionInfo
+	fmt.Printf("Go %v %s/%s in %s\n", s.Local, s.GOOS, s.Arch, s.GoRoot)
+	if s.Latest != zero {
+		fmt.Printf("Latest release: %v\n", s.Latest)
+	}
+	if available, known := s.UpdateAvailable(); !known {
+		fmt.Println("Update status unknown, version list is not available")
+	} else if available {
+		fmt.Printf("Update available under %s policy: %v\n", s.Policy, s.Target)
+	} else {
+		fmt.Printf("Up to date under %s policy\n", s.Policy)
+	}
+	if c := s.LastCheck; c != nil {
+		fmt.Printf("Last check: %s, %s\n", c.CheckedAt.Format(time.RFC3339), c.Message())
+	}
+	if u := s.LastUpgrade; u != nil {
+		result := "succeeded"
+		if !u.Success {
+			result = "failed"
+		}
+		fmt.Printf("Last upgrade: %s, %v to %v in %s %s\n", u.Time.Format(time.RFC3339), u.From, u.To, u.GoRoot, result)
+	}
+}
+
+func serveStatus(cache *versionCache) {
+	http.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		s, err := collectStatus(cache)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		goup.WriteMetrics(w, s)
+	})
+	http.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		s, err := collectStatus(cache)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		available, _ := s.UpdateAvailable()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			goup.Status
+			UpdateAvailable bool
+		}{s, available})
+	})
+	fmt.Printf("Serving metrics on %s/metrics\n", *statusServe)
+	if err := http.ListenAndServe(*statusServe, nil); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// recordUpgrade keeps the outcome of upgrade for goup status
+func recordUpgrade(gopath string, from, to goup.VersionInfo, ok bool) {
+	err := goup.SaveUpgradeRecord(goup.UpgradeRecord{Time: time.Now(), GoRoot: gopath, From: from, To: to, Success: ok})
+	if err != nil {
+		printVerbose("%v\n", err)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mkishere/goup"
+	kingpin "gopkg.in/alecthomas/kingpin.v2"
+)
+
+var (
+	toolsCmd        = kingpin.Command("tools", "List and rebuild executables installed with go install.")
+	toolsGo         = toolsCmd.Flag("go", "Go executable whose GOBIN is checked and which rebuilds tools.").Default("go").String()
+	toolsListCmd    = toolsCmd.Command("list", "List tools in GOBIN with the Go version they are built with.")
+	toolsRebuildCmd = toolsCmd.Command("rebuild", "Rebuild tools built with older Go using go install path@version.")
+	toolsAll        = toolsRebuildCmd.Flag("all", "Rebuild tools built with current Go too.").Bool()
+	toolsNames      = toolsRebuildCmd.Arg("tool", "Tools to rebuild. Defaults to every tool built with older Go.").Strings()
+)
+
+// toolsEnv reads GOBIN and version of go executable
+func toolsEnv(goExe string) (string, goup.VersionInfo, error) {
+	env, err := goup.ReadGoEnv(goExe)
+	if err != nil {
+		return "", goup.VersionInfo{}, err
+	}
+	gv, err := goup.LocalGoVersion(env.Executable)
+	if err != nil {
+		return "", goup.VersionInfo{}, err
+	}
+	return env.BinDir(), gv.Version, nil
+}
+
+func toolsList() {
+	bin, ver, err := toolsEnv(*toolsGo)
+	if err != nil {
+		fmt.Println("Error when getting local Go infomration", err)
+		os.Exit(1)
+	}
+	tools, err := goup.ScanTools(bin)
+	if err != nil {
+		fmt.Println("Cannot list tools:", err)
+		os.Exit(1)
+	}
+	if len(tools) == 0 {
+		fmt.Printf("No Go executables in %s\n", bin)
+		return
+	}
+	for _, t := range tools {
+		status := ""
+		if _, err := t.Target(); err != nil {
+			status = "local build, cannot be rebuilt"
+		} else if t.BuiltBefore(ver) {
+			status = "built with older Go"
+		}
+		fmt.Printf("%-20s go%-10v %s@%s  %s\n", t.Name(), t.GoVersion, t.Package, t.Version, status)
+	}
+}
+
+func toolsRebuild() {
+	bin, ver, err := toolsEnv(*toolsGo)
+	if err != nil {
+		fmt.Println("Error when getting local Go infomration", err)
+		os.Exit(1)
+	}
+	tools, err := goup.ScanTools(bin)
+	if err != nil {
+		fmt.Println("Cannot list tools:", err)
+		os.Exit(1)
+	}
+	selected := make([]goup.Tool, 0, len(tools))
+	for _, t := range tools {
+		switch {
+		case len(*toolsNames) > 0:
+			if containsString(*toolsNames, t.Name()) || containsString(*toolsNames, strings.TrimSuffix(t.Name(), ".exe")) {
+				selected = append(selected, t)
+			}
+		case *toolsAll || t.BuiltBefore(ver):
+			selected = append(selected, t)
+		}
+	}
+	if len(selected) == 0 {
+		fmt.Printf("No tools in %s need rebuilding\n", bin)
+		return
+	}
+	if !rebuildTools(*toolsGo, ver, selected) {
+		os.Exit(1)
+	}
+}
+
+// offerToolRebuild asks to rebuild tools built with Go older than the one
+// just installed at goExe
+func offerToolRebuild(goExe string) {
+	bin, ver, err := toolsEnv(goExe)
+	if err != nil {
+		printVerbose("Cannot check tools: %v\n", err)
+		return
+	}
+	tools, err := goup.ScanTools(bin)
+	if err != nil {
+		printVerbose("Cannot check tools: %v\n", err)
+		return
+	}
+	stale := make([]goup.Tool, 0, len(tools))
+	names := make([]string, 0, len(tools))
+	for _, t := range tools {
+		if _, err := t.Target(); err == nil && t.BuiltBefore(ver) {
+			stale = append(stale, t)
+			names = append(names, t.Name())
+		}
+	}
+	if len(stale) == 0 {
+		return
+	}
+	fmt.Printf("Tools in %s built with older Go: %s\n", bin, strings.Join(names, ", "))
+	if *autoUpd || !confirm(fmt.Sprintf("Rebuild them with Go %v now (Y/n):", ver)) {
+		fmt.Println("Run \"goup tools rebuild\" to rebuild them later")
+		return
+	}
+	rebuildTools(goExe, ver, stale)
+}
+
+// rebuildTools rebuilds tools and reports the result of each. Returns true if
+// all are rebuilt.
+func rebuildTools(goExe string, ver goup.VersionInfo, tools []goup.Tool) bool {
+	var rebuilt, failed, skipped []string
+	for _, t := range tools {
+		if _, err := t.Target(); err != nil {
+			skipped = append(skipped, t.Name())
+			continue
+		}
+		fmt.Printf("Rebuilding %s (%s@%s) with Go %v\n", t.Name(), t.Package, t.Version, ver)
+		if err := goup.RebuildTool(goExe, t); err != nil {
+			fmt.Println(err)
+			failed = append(failed, t.Name())
+			continue
+		}
+		rebuilt = append(rebuilt, t.Name())
+	}
+	if len(rebuilt) > 0 {
+		fmt.Printf("Rebuilt: %s\n", strings.Join(rebuilt, ", "))
+	}
+	if len(skipped) > 0 {
+		fmt.Printf("Skipped local builds: %s\n", strings.Join(skipped, ", "))
+	}
+	if len(failed) > 0 {
+		fmt.Printf("Failed: %s\n", strings.Join(failed, ", "))
+		return false
+	}
+	return true
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
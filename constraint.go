@@ -0,0 +1,86 @@
+package goup
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Constraint is a set of version requirements separated by comma, e.g.
+// ">=1.20, <1.22". A requirement without operator matches the exact version
+// if build number is given (1.21.5), or any version of the minor release
+// otherwise (1.21). "~1.21.3" matches 1.21.3 and later patches of 1.21.
+type Constraint struct {
+	raw   string
+	terms []constraintTerm
+}
+
+type constraintTerm struct {
+	op       string
+	ver      VersionInfo
+	hasBuild bool
+}
+
+// ParseConstraint parses version constraint string
+func ParseConstraint(s string) (Constraint, error) {
+	c := Constraint{raw: strings.TrimSpace(s)}
+	if c.raw == "" {
+		return Constraint{}, fmt.Errorf("Empty version constraint")
+	}
+	for _, part := range strings.Split(c.raw, ",") {
+		part = strings.TrimSpace(part)
+		op := ""
+		for _, o := range []string{">=", "<=", ">", "<", "=", "~"} {
+			if strings.HasPrefix(part, o) {
+				op = o
+				break
+			}
+		}
+		verStr := strings.TrimPrefix(strings.TrimSpace(part[len(op):]), "go")
+		ver, err := ExtractVersionInfo(verStr)
+		if err != nil {
+			return Constraint{}, fmt.Errorf("Invalid version %q in constraint: %v", verStr, err)
+		}
+		c.terms = append(c.terms, constraintTerm{
+			op:       op,
+			ver:      ver,
+			hasBuild: strings.Count(verStr, ".") == 2,
+		})
+	}
+	return c, nil
+}
+
+func (c Constraint) String() string {
+	return c.raw
+}
+
+// Match tells if the version satisfies all requirements of the constraint
+func (c Constraint) Match(vi VersionInfo) bool {
+	for _, t := range c.terms {
+		if !t.match(vi) {
+			return false
+		}
+	}
+	return len(c.terms) > 0
+}
+
+func (t constraintTerm) match(vi VersionInfo) bool {
+	cmp := CompareVersion(vi, t.ver)
+	sameMinor := vi.Major == t.ver.Major && vi.Minor == t.ver.Minor
+	switch t.op {
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	case "~":
+		return sameMinor && cmp >= 0
+	default:
+		if t.hasBuild || t.ver.Beta || t.ver.RC {
+			return cmp == 0
+		}
+		return sameMinor
+	}
+}
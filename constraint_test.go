@@ -0,0 +1,44 @@
+package goup
+
+import (
+	"testing"
+)
+
+func TestConstraint_Match(t *testing.T) {
+	tests := []struct {
+		name       string
+		constraint string
+		version    string
+		want       bool
+	}{
+		{"TestCase 1", "1.21", "1.21.4", true},
+		{"TestCase 2", "1.21", "1.22.1", false},
+		{"TestCase 3", "1.21.4", "1.21.4", true},
+		{"TestCase 4", "1.21.4", "1.21.5", false},
+		{"TestCase 5", ">=1.20, <1.22", "1.21.1", true},
+		{"TestCase 6", ">=1.20, <1.22", "1.22", false},
+		{"TestCase 7", "~1.21.3", "1.21.2", false},
+		{"TestCase 8", "~1.21.3", "1.21.9", true},
+		{"TestCase 9", ">go1.21", "1.21.1", true},
+		{"TestCase 10", "<=1.21rc2", "1.21rc1", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseConstraint(tt.constraint)
+			if err != nil {
+				t.Fatalf("ParseConstraint() error = %v", err)
+			}
+			if got := c.Match(mustVersion(t, tt.version)); got != tt.want {
+				t.Errorf("Constraint.Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseConstraint(t *testing.T) {
+	for _, s := range []string{"", "1", ">=", "1.x", ">=1.20,"} {
+		if _, err := ParseConstraint(s); err == nil {
+			t.Errorf("ParseConstraint(%q) expected error", s)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package goup
+
+import (
+	"os"
+	"syscall"
+)
+
+// FICLONE ioctl request, see ioctl_ficlone(2)
+const ficlone = 0x40049409
+
+// cloneFile makes dst share the data blocks of src on file systems
+// supporting copy-on-write (btrfs, xfs)
+func cloneFile(dst, src *os.File) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, dst.Fd(), ficlone, src.Fd())
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+// +build !linux
+
+package goup
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+var errCloneUnsupported = errors.New("Copy-on-write clone is not supported")
+
+// cloneFile is not supported outside Linux
+func cloneFile(dst, src *os.File) error {
+	return errCloneUnsupported
+}
@@ -0,0 +1,309 @@
+package goup
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Severity tells how serious a doctor Finding is
+type Severity int
+
+const (
+	// SeverityOK means the check passed
+	SeverityOK Severity = iota
+	// SeverityWarning means goup works but something may surprise user
+	SeverityWarning
+	// SeverityProblem means Go or goup does not work as expected
+	SeverityProblem
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityProblem:
+		return "problem"
+	}
+	return "ok"
+}
+
+// Finding is the result of a doctor check
+type Finding struct {
+	Check    string
+	Severity Severity
+	Message  string
+	// Fix suggests how to resolve a warning or problem
+	Fix string
+}
+
+// DoctorOptions configures Doctor
+type DoctorOptions struct {
+	// Path is the list of directories go is looked up in, in $PATH format
+	Path string
+	// Sources are URLs of version list and downloads checked for
+	// reachability, none are checked if empty
+	Sources []string
+}
+
+// DefaultSources are the URLs goup fetches versions from and, unless version
+// is zero, the archive of version for goos and arch it would download
+func DefaultSources(version VersionInfo, goos, arch string) []string {
+	if version == (VersionInfo{}) {
+		return []string{RelVerURL}
+	}
+	return []string{RelVerURL, DownloadUrl(version, goos, arch)}
+}
+
+// pmRoot is where a package manager installs Go
+type pmRoot struct {
+	pattern string
+	manager string
+}
+
+// packageManagerRoots are Go installations of package managers, which goup
+// should not upgrade
+var packageManagerRoots = []pmRoot{
+	{"/usr/lib/go", "apt (golang-go)"},
+	{"/usr/lib/go-1.*", "apt (golang-1.x-go)"},
+	{"/usr/lib/golang", "dnf or yum (golang)"},
+	{"/usr/local/Cellar/go/*/libexec", "Homebrew"},
+	{"/opt/homebrew/Cellar/go/*/libexec", "Homebrew"},
+	{"/snap/go/current", "snap"},
+}
+
+// Doctor checks the Go installations on path and goup state, returning what
+// is found in order of the checks
+func Doctor(opts DoctorOptions) []Finding {
+	findings, exe := checkGoOnPath(opts.Path)
+	goroot := ""
+	if exe != "" {
+		env, envFindings := checkGoEnv(exe, opts.Path)
+		findings = append(findings, envFindings...)
+		goroot = env.GOROOT
+	}
+	findings = append(findings, checkPackageManagers(goroot, packageManagerRoots)...)
+	findings = append(findings, checkLeftovers(goroot)...)
+	if len(opts.Sources) > 0 {
+		findings = append(findings, checkSources(opts.Sources)...)
+	}
+	return findings
+}
+
+// FindGoOnPath lists go executables in directories of path in PATH order
+func FindGoOnPath(path string) []string {
+	found := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			continue
+		}
+		exe := filepath.Join(dir, "go"+exeSuffix(runtime.GOOS))
+		fi, err := os.Stat(exe)
+		if err != nil || fi.IsDir() || (runtime.GOOS != "windows" && fi.Mode()&0111 == 0) {
+			continue
+		}
+		if seen[exe] {
+			continue
+		}
+		seen[exe] = true
+		found = append(found, exe)
+	}
+	return found
+}
+
+// checkGoOnPath reports every go on path and its version, returning the one
+// used, which is the first
+func checkGoOnPath(path string) ([]Finding, string) {
+	exes := FindGoOnPath(path)
+	if len(exes) == 0 {
+		return []Finding{{"go on PATH", SeverityProblem, "No go executable on PATH",
+			"Add bin directory of your Go installation to PATH, or install one with goup install <version> and run goup shim install"}}, ""
+	}
+	findings := make([]Finding, 0, len(exes)+1)
+	targets := make(map[string]bool)
+	for i, exe := range exes {
+		used := ""
+		if i == 0 {
+			used = " (used)"
+		}
+		if filepath.Dir(exe) == ShimsDir() {
+			f := Finding{"go on PATH", SeverityOK, fmt.Sprintf("goup shim at %s%s", exe, used), ""}
+			if res, err := Resolve("."); err == nil {
+				f.Message += fmt.Sprintf(", runs go%v here", res.Version)
+			} else {
+				f.Severity, f.Message = SeverityProblem, f.Message+": "+err.Error()
+				f.Fix = "Set the version with goup default <version> and install it with goup install"
+			}
+			findings = append(findings, f)
+			continue
+		}
+		if target, err := filepath.EvalSymlinks(exe); err == nil {
+			targets[target] = true
+		}
+		ver, goos, arch, err := LocalGoInfo(exe)
+		if err != nil {
+			findings = append(findings, Finding{"go on PATH", SeverityProblem, fmt.Sprintf("%s cannot be run: %v", exe, err),
+				"Reinstall this Go or remove its directory from PATH"})
+			continue
+		}
+		findings = append(findings, Finding{"go on PATH", SeverityOK, fmt.Sprintf("go%v %s/%s at %s%s", ver, goos, arch, exe, used), ""})
+	}
+	if len(targets) > 1 {
+		findings = append(findings, Finding{"go on PATH", SeverityWarning,
+			fmt.Sprintf("%d different Go installations are on PATH, only %s is used", len(targets), exes[0]),
+			"Remove the others from PATH or uninstall them to avoid confusion"})
+	}
+	// GOROOT of shims depends on directory, goup which explains it
+	if filepath.Dir(exes[0]) == ShimsDir() {
+		return findings, ""
+	}
+	return findings, exes[0]
+}
+
+// checkGoEnv checks GOROOT of exe matches its location and GOBIN is on path
+func checkGoEnv(exe, path string) (GoEnv, []Finding) {
+	env, err := ReadGoEnv(exe)
+	findings := make([]Finding, 0, 2)
+	switch err.(type) {
+	case nil:
+		findings = append(findings, Finding{"GOROOT", SeverityOK, fmt.Sprintf("GOROOT %s matches %s", env.GOROOT, env.Executable), ""})
+	case *RootMismatchError:
+		fix := "Check GOTOOLCHAIN in go env -w settings, or reinstall Go"
+		if os.Getenv("GOROOT") != "" {
+			fix = "Unset GOROOT in your environment and shell profile, go finds its root by itself"
+		}
+		findings = append(findings, Finding{"GOROOT", SeverityProblem, err.Error(), fix})
+	default:
+		return env, append(findings, Finding{"GOROOT", SeverityProblem, fmt.Sprintf("Cannot read environment of %s: %v", exe, err),
+			"Reinstall this Go"})
+	}
+
+	bin := env.BinDir()
+	if bin == "" {
+		return env, findings
+	}
+	onPath := false
+	for _, dir := range filepath.SplitList(path) {
+		if dir != "" && filepath.Clean(dir) == filepath.Clean(bin) {
+			onPath = true
+		}
+	}
+	if onPath {
+		findings = append(findings, Finding{"GOBIN", SeverityOK, fmt.Sprintf("%s is on PATH", bin), ""})
+	} else {
+		fix := fmt.Sprintf("Add it to PATH, e.g. export PATH=\"$PATH:%s\" in your shell profile", bin)
+		if runtime.GOOS == "windows" {
+			fix = fmt.Sprintf("Add it to PATH, e.g. setx PATH \"%%PATH%%;%s\"", bin)
+		}
+		findings = append(findings, Finding{"GOBIN", SeverityWarning,
+			fmt.Sprintf("%s, where go install puts tools, is not on PATH", bin), fix})
+	}
+	return env, findings
+}
+
+// checkPackageManagers reports Go installed by package managers in roots
+func checkPackageManagers(goroot string, roots []pmRoot) []Finding {
+	active := goroot
+	if r, err := filepath.EvalSymlinks(goroot); err == nil {
+		active = r
+	}
+	findings := make([]Finding, 0)
+	seen := make(map[string]bool)
+	for _, pm := range roots {
+		matches, _ := filepath.Glob(pm.pattern)
+		for _, root := range matches {
+			if r, err := filepath.EvalSymlinks(root); err == nil {
+				root = r
+			}
+			if seen[root] || !isDir(filepath.Join(root, "bin")) {
+				continue
+			}
+			seen[root] = true
+			if root == active {
+				findings = append(findings, Finding{"package manager", SeverityWarning,
+					fmt.Sprintf("Go in use at %s is installed by %s, which may undo or conflict with goup upgrades", root, pm.manager),
+					fmt.Sprintf("Upgrade it with %s, or install Go with goup install and run goup shim install", pm.manager)})
+				continue
+			}
+			findings = append(findings, Finding{"package manager", SeverityWarning,
+				fmt.Sprintf("Another Go is installed by %s at %s", pm.manager, root),
+				fmt.Sprintf("Remove it with %s if it is not needed", pm.manager)})
+		}
+	}
+	return findings
+}
+
+// checkLeftovers reports interrupted upgrades and files goup clean removes
+func checkLeftovers(goroot string) []Finding {
+	findings := make([]Finding, 0)
+	journals, err := PendingJournals()
+	if err != nil {
+		findings = append(findings, Finding{"upgrade journal", SeverityProblem, err.Error(),
+			fmt.Sprintf("Remove the broken journal from %s", JournalDir())})
+	}
+	for _, j := range journals {
+		findings = append(findings, Finding{"upgrade journal", SeverityProblem,
+			fmt.Sprintf("Upgrade of %s from %v to %v was interrupted at %s phase", j.TargetPath, j.FromVersion, j.ToVersion, j.Phase),
+			"Run goup to recover it"})
+	}
+
+	opts := CleanOptions{BackupAge: 7 * 24 * time.Hour}
+	if goroot != "" {
+		opts.Roots = []string{goroot}
+	}
+	items, err := FindLeftovers(opts)
+	if err != nil {
+		return append(findings, Finding{"leftovers", SeverityWarning, err.Error(), ""})
+	}
+	if len(items) == 0 {
+		return append(findings, Finding{"leftovers", SeverityOK, "No leftover backups, downloads or temporary files", ""})
+	}
+	var size int64
+	counts := make(map[CleanKind]int)
+	kinds := make([]string, 0)
+	for _, item := range items {
+		size += item.Size
+		if counts[item.Kind] == 0 {
+			kinds = append(kinds, string(item.Kind))
+		}
+		counts[item.Kind]++
+	}
+	for i, kind := range kinds {
+		kinds[i] = fmt.Sprintf("%d %s", counts[CleanKind(kind)], kind)
+	}
+	return append(findings, Finding{"leftovers", SeverityWarning,
+		fmt.Sprintf("%s use %s", strings.Join(kinds, ", "), FormatBytes(size)),
+		"Run goup clean --dry-run to list them and goup clean to remove them"})
+}
+
+// checkSources checks each URL answers an HTTP request successfully
+func checkSources(urls []string) []Finding {
+	client := &http.Client{Transport: httpClient.Transport, Timeout: 10 * time.Second}
+	findings := make([]Finding, 0, len(urls))
+	for _, url := range urls {
+		resp, err := client.Head(url)
+		if err != nil {
+			findings = append(findings, Finding{"version source", SeverityProblem, fmt.Sprintf("Cannot reach %s: %v", url, err),
+				"Check network connection, and HTTPS_PROXY if you are behind a proxy"})
+			continue
+		}
+		resp.Body.Close()
+		switch {
+		case resp.StatusCode >= 500:
+			findings = append(findings, Finding{"version source", SeverityWarning, fmt.Sprintf("%s answers %s", url, resp.Status),
+				"Try again later"})
+			continue
+		case resp.StatusCode >= 400:
+			findings = append(findings, Finding{"version source", SeverityProblem, fmt.Sprintf("%s answers %s", url, resp.Status),
+				"Check the URL is right, and HTTPS_PROXY if you are behind a proxy"})
+			continue
+		}
+		findings = append(findings, Finding{"version source", SeverityOK, fmt.Sprintf("%s is reachable", url), ""})
+	}
+	return findings
+}
@@ -0,0 +1,179 @@
+package goup
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeGoVersion writes go executable in dir printing version
+func fakeGoVersion(t *testing.T, dir, version string) string {
+	t.Helper()
+	os.MkdirAll(dir, 0755)
+	exe := filepath.Join(dir, "go")
+	script := "#!/bin/sh\necho go version go" + version + " linux/amd64\n"
+	if err := ioutil.WriteFile(exe, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return exe
+}
+
+func severities(findings []Finding) []Severity {
+	s := make([]Severity, len(findings))
+	for i, f := range findings {
+		s[i] = f.Severity
+	}
+	return s
+}
+
+func TestCheckGoOnPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Shell script as go executable is not supported on Windows")
+	}
+	dir, err := ioutil.TempDir("", "goup-doctor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	setGoupHome(t, filepath.Join(dir, "home"))
+
+	first := fakeGoVersion(t, filepath.Join(dir, "a"), "1.22.1")
+	fakeGoVersion(t, filepath.Join(dir, "b"), "1.21.5")
+	os.MkdirAll(filepath.Join(dir, "c"), 0755)
+	os.Symlink(first, filepath.Join(dir, "c", "go"))
+	os.MkdirAll(filepath.Join(dir, "broken"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "broken", "go"), []byte("#!/bin/sh\nexit 1\n"), 0755)
+	os.MkdirAll(filepath.Join(dir, "noexec"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "noexec", "go"), []byte(""), 0644)
+	sep := string(os.PathListSeparator)
+	join := func(dirs ...string) string {
+		for i := range dirs {
+			dirs[i] = filepath.Join(dir, dirs[i])
+		}
+		return strings.Join(dirs, sep)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantExe string
+		want    []Severity
+	}{
+		{"TestCase 1", join("a"), first, []Severity{SeverityOK}},
+		{"TestCase 2", join("a", "c", "a"), first, []Severity{SeverityOK, SeverityOK}},
+		{"TestCase 3", join("a", "b", "noexec"), first, []Severity{SeverityOK, SeverityOK, SeverityWarning}},
+		{"TestCase 4", join("broken", "a"), filepath.Join(dir, "broken", "go"), []Severity{SeverityProblem, SeverityOK, SeverityWarning}},
+		{"TestCase 5", join("noexec"), "", []Severity{SeverityProblem}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings, exe := checkGoOnPath(tt.path)
+			if exe != tt.wantExe {
+				t.Errorf("checkGoOnPath() exe = %v, want %v", exe, tt.wantExe)
+			}
+			got := severities(findings)
+			if len(got) != len(tt.want) {
+				t.Fatalf("checkGoOnPath() = %+v, want severities %v", findings, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("checkGoOnPath() = %+v, want severities %v", findings, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckGoEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Shell script as go executable is not supported on Windows")
+	}
+	dir, err := ioutil.TempDir("", "goup-doctor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dir, _ = filepath.EvalSymlinks(dir)
+
+	exe := fakeGo(t, dir, dir)
+	env, findings := checkGoEnv(exe, "/home/gopher/go/bin")
+	if env.GOROOT != dir || len(findings) != 2 || findings[0].Severity != SeverityOK || findings[1].Severity != SeverityOK {
+		t.Errorf("checkGoEnv() = %+v", findings)
+	}
+	_, findings = checkGoEnv(exe, "/usr/bin")
+	if len(findings) != 2 || findings[1].Severity != SeverityWarning || findings[1].Fix == "" {
+		t.Errorf("checkGoEnv() with GOBIN not on PATH = %+v", findings)
+	}
+	exe = fakeGo(t, filepath.Join(dir, "other"), dir)
+	_, findings = checkGoEnv(exe, "/usr/bin")
+	if findings[0].Severity != SeverityProblem {
+		t.Errorf("checkGoEnv() with GOROOT mismatch = %+v", findings)
+	}
+}
+
+func TestCheckPackageManagers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goup-doctor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dir, _ = filepath.EvalSymlinks(dir)
+	for _, d := range []string{"lib/go-1.21/bin", "lib/go-1.22/bin", "lib/go-empty", "local/go/bin"} {
+		os.MkdirAll(filepath.Join(dir, filepath.FromSlash(d)), 0755)
+	}
+	roots := []pmRoot{{filepath.Join(dir, "lib", "go-*"), "apt"}, {filepath.Join(dir, "nothing"), "brew"}}
+
+	findings := checkPackageManagers(filepath.Join(dir, "local", "go"), roots)
+	if len(findings) != 2 || !strings.Contains(findings[0].Message, "Another Go") {
+		t.Errorf("checkPackageManagers() = %+v", findings)
+	}
+	findings = checkPackageManagers(filepath.Join(dir, "lib", "go-1.22"), roots)
+	if len(findings) != 2 || !strings.Contains(findings[1].Message, "in use") {
+		t.Errorf("checkPackageManagers() of active package = %+v", findings)
+	}
+}
+
+func TestCheckLeftovers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goup-doctor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	setGoupHome(t, filepath.Join(dir, "home"))
+
+	os.MkdirAll(DownloadsDir(), 0755)
+	ioutil.WriteFile(filepath.Join(DownloadsDir(), "go1.22.1.linux-amd64.tar.gz"), []byte("1234"), 0644)
+	if _, err := NewJournal(filepath.Join(dir, "go"), filepath.Join(dir, "backup"), mustVersion(t, "1.21.1"), mustVersion(t, "1.21.2")); err != nil {
+		t.Fatal(err)
+	}
+	findings := checkLeftovers("")
+	if len(findings) != 2 || findings[0].Severity != SeverityProblem || findings[1].Severity != SeverityWarning {
+		t.Errorf("checkLeftovers() = %+v", findings)
+	}
+}
+
+func TestCheckSources(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ok.Close()
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	got := severities(checkSources([]string{ok.URL, missing.URL, failing.URL, closed.URL}))
+	want := []Severity{SeverityOK, SeverityProblem, SeverityWarning, SeverityProblem}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] || got[3] != want[3] {
+		t.Errorf("checkSources() = %v, want %v", got, want)
+	}
+}
@@ -1,95 +1,336 @@
-package goup
-
-import (
-	"fmt"
-	"io"
-	"os"
-	"path/filepath"
-
-	"github.com/pkg/errors"
-
-	"github.com/karrick/godirwalk"
-)
-
-const (
-	BufSize = 10 * 1024
-)
-
-func copyFile(src, dst string) error {
-	srcFile, err := os.Open(src)
-	if err != nil {
-		return errors.Wrap(err, "Cannot open src")
-	}
-	defer srcFile.Close()
-
-	srcAttr, err := srcFile.Stat()
-	if err != nil {
-		return errors.Wrap(err, "Cannot get src attributes")
-	}
-	_, err = os.Stat(dst)
-	if err == nil {
-		return fmt.Errorf("File %s alread exists", dst)
-	}
-
-	dstFile, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY, srcAttr.Mode())
-	if err != nil {
-		err = os.MkdirAll(filepath.Dir(dst), 0666)
-		if err != nil {
-			return errors.Wrap(err, "Error when creating parent directory in target location")
-		}
-		dstFile, err = os.Create(dst)
-		if err != nil {
-			return err
-		}
-	}
-	defer dstFile.Close()
-
-	buf := make([]byte, BufSize)
-	for {
-		n, err := srcFile.Read(buf)
-		if err != nil && err != io.EOF {
-			return err
-		}
-		if n == 0 {
-			break
-		}
-
-		if _, err := dstFile.Write(buf[:n]); err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-func RecursiveCopyDir(src, dst string) error {
-	buf := make([]byte, BufSize)
-	baseDirLen := len(src)
-	err := godirwalk.Walk(src, &godirwalk.Options{
-		ScratchBuffer: buf,
-		Callback: func(osPathname string, de *godirwalk.Dirent) error {
-			if de.IsDir() {
-				return nil
-			}
-			newPath := filepath.Join(dst, osPathname[baseDirLen:])
-			err := copyFile(osPathname, newPath)
-			if err != nil {
-				fmt.Println("Error: ", err)
-			}
-			return err
-		},
-		PostChildrenCallback: func(osPathname string, de *godirwalk.Dirent) error {
-			deChildren, err := godirwalk.ReadDirents(osPathname, buf)
-			if err != nil {
-				return err
-			}
-
-			if len(deChildren) > 0 {
-				return nil
-			}
-
-			return os.MkdirAll(filepath.Join(dst, osPathname[baseDirLen:]), 0666)
-		},
-	})
-
-	return err
-}
+package goup
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/karrick/godirwalk"
+)
+
+const (
+	BufSize = 10 * 1024
+)
+
+// CopyFailure is a path failed to copy and the cause
+type CopyFailure struct {
+	Path string
+	Err  error
+}
+
+// CopyError is returned by RecursiveCopyDir listing every path failed to
+// copy. Copying continues after a failure so the error covers the whole tree.
+type CopyError struct {
+	Failures []CopyFailure
+}
+
+func (e *CopyError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d path(s) failed to copy:", len(e.Failures))
+	for _, f := range e.Failures {
+		fmt.Fprintf(&sb, "\n  %s: %v", f.Path, f.Err)
+	}
+	return sb.String()
+}
+
+func (e *CopyError) add(path string, err error) {
+	e.Failures = append(e.Failures, CopyFailure{Path: path, Err: err})
+}
+
+// CopyStrategy decides how file content is copied
+type CopyStrategy int
+
+const (
+	// CopyAuto tries copy-on-write clone (FICLONE on Linux btrfs/xfs), then
+	// in-kernel copy (copy_file_range on Linux), then plain read/write
+	CopyAuto CopyStrategy = iota
+	// CopyPlain copies file content with read/write only
+	CopyPlain
+	// CopyHardlink links files to the source instead of copying. Files which
+	// cannot be linked (e.g. across file systems) are copied as CopyAuto and
+	// reported through CopyOptions.Log. As the copy shares data with the
+	// source, the source must not be modified in place.
+	CopyHardlink
+)
+
+// ParseCopyStrategy converts strategy name (auto, copy or hardlink) to
+// CopyStrategy
+func ParseCopyStrategy(name string) (CopyStrategy, error) {
+	switch name {
+	case "", "auto":
+		return CopyAuto, nil
+	case "copy":
+		return CopyPlain, nil
+	case "hardlink":
+		return CopyHardlink, nil
+	}
+	return CopyAuto, fmt.Errorf("Unknown copy strategy %q", name)
+}
+
+// CopyOptions controls RecursiveCopyDirWithOptions
+type CopyOptions struct {
+	// Workers is the number of files copied concurrently, defaults to number
+	// of CPUs
+	Workers  int
+	Strategy CopyStrategy
+	// Log is told when CopyHardlink falls back to copying, if not nil
+	Log func(format string, arg ...interface{})
+}
+
+// copyFile copies regular file src to dst, overwriting dst if exists, and
+// preserves mode, owner (if permitted) and modification time
+func copyFile(src, dst string, srcAttr os.FileInfo, strategy CopyStrategy) error {
+	// Existing file may be read-only
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "Cannot replace existing file")
+	}
+	if strategy == CopyHardlink {
+		return errors.Wrap(os.Link(src, dst), "Cannot hardlink file")
+	}
+
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return errors.Wrap(err, "Cannot open src")
+	}
+	defer srcFile.Close()
+
+	dstFile, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+	if err != nil {
+		return errors.Wrap(err, "Cannot create file")
+	}
+
+	if strategy == CopyPlain {
+		// Hide ReaderFrom/WriterTo so io.CopyBuffer does not use in-kernel copy
+		buf := make([]byte, BufSize)
+		_, err = io.CopyBuffer(struct{ io.Writer }{dstFile}, struct{ io.Reader }{srcFile}, buf)
+	} else if err = cloneFile(dstFile, srcFile); err != nil {
+		// os.File.ReadFrom uses copy_file_range where available
+		_, err = io.Copy(dstFile, srcFile)
+	}
+	if closeErr := dstFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Wrap(err, "Cannot write data")
+	}
+	return copyAttributes(dst, srcAttr)
+}
+
+// copyAttributes applies mode, owner and modification time of srcAttr to
+// path. Ownership is only changed when permitted.
+func copyAttributes(path string, srcAttr os.FileInfo) error {
+	if err := os.Chmod(path, srcAttr.Mode()&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky)); err != nil {
+		return errors.Wrap(err, "Cannot set mode")
+	}
+	if err := copyOwner(path, srcAttr); err != nil {
+		return errors.Wrap(err, "Cannot set owner")
+	}
+	if err := os.Chtimes(path, srcAttr.ModTime(), srcAttr.ModTime()); err != nil {
+		return errors.Wrap(err, "Cannot set modification time")
+	}
+	return nil
+}
+
+// copySymlink recreates symbolic link src at dst pointing to the same target
+func copySymlink(src, dst string, srcAttr os.FileInfo) error {
+	target, err := os.Readlink(src)
+	if err != nil {
+		return errors.Wrap(err, "Cannot read link")
+	}
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "Cannot replace existing file")
+	}
+	if err := os.Symlink(target, dst); err != nil {
+		return errors.Wrap(err, "Cannot create link")
+	}
+	if err := copyOwner(dst, srcAttr); err != nil {
+		return errors.Wrap(err, "Cannot set owner")
+	}
+	return nil
+}
+
+// RecursiveCopyDir copies directory src to dst with default CopyOptions
+func RecursiveCopyDir(src, dst string) error {
+	return RecursiveCopyDirWithOptions(src, dst, CopyOptions{})
+}
+
+// RecursiveCopyDirWithOptions copies directory src to dst. Modes,
+// modification times, ownership (where permitted) and symbolic links are
+// preserved. Failures do not stop the copy; they are returned together as
+// *CopyError.
+func RecursiveCopyDirWithOptions(src, dst string, opts CopyOptions) error {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	copyErr := &CopyError{}
+	var errMu sync.Mutex
+	addErr := func(path string, err error) {
+		errMu.Lock()
+		copyErr.add(path, err)
+		errMu.Unlock()
+	}
+
+	type fileJob struct {
+		src, dst string
+		attr     os.FileInfo
+	}
+	var fallback sync.Once
+	copyJob := func(src, dst string, attr os.FileInfo) error {
+		err := copyFile(src, dst, attr, opts.Strategy)
+		if err != nil && opts.Strategy == CopyHardlink {
+			fallback.Do(func() {
+				if opts.Log != nil {
+					opts.Log("Cannot hardlink %s (%v), copying files instead\n", src, err)
+				}
+			})
+			err = copyFile(src, dst, attr, CopyAuto)
+		}
+		return err
+	}
+
+	jobs := make(chan fileJob, workers*4)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if err := copyJob(job.src, job.dst, job.attr); err != nil {
+					addErr(job.src, err)
+				}
+			}
+		}()
+	}
+
+	// Directory attributes are applied after all files are copied, deepest
+	// first, as copying into a directory changes its modification time and
+	// it may be read-only
+	type dirAttr struct {
+		src, dst string
+	}
+	dirs := make([]dirAttr, 0)
+
+	buf := make([]byte, BufSize)
+	err := godirwalk.Walk(src, &godirwalk.Options{
+		ScratchBuffer: buf,
+		Callback: func(osPathname string, de *godirwalk.Dirent) error {
+			newPath, err := targetPath(src, dst, osPathname)
+			if err != nil {
+				addErr(osPathname, err)
+				return nil
+			}
+			srcAttr, err := os.Lstat(osPathname)
+			if err != nil {
+				addErr(osPathname, err)
+				return nil
+			}
+
+			switch mode := srcAttr.Mode(); {
+			case mode.IsDir():
+				err = os.MkdirAll(newPath, 0700)
+				if err == nil {
+					err = os.Chmod(newPath, 0700)
+				}
+				if err != nil {
+					addErr(osPathname, errors.Wrap(err, "Cannot create directory"))
+					return filepath.SkipDir
+				}
+			case mode&os.ModeSymlink != 0:
+				err = copySymlink(osPathname, newPath, srcAttr)
+			case mode.IsRegular():
+				jobs <- fileJob{osPathname, newPath, srcAttr}
+			default:
+				err = fmt.Errorf("Unsupported file type %v", mode.Type())
+			}
+			if err != nil {
+				addErr(osPathname, err)
+			}
+			return nil
+		},
+		PostChildrenCallback: func(osPathname string, de *godirwalk.Dirent) error {
+			newPath, err := targetPath(src, dst, osPathname)
+			if err == nil {
+				dirs = append(dirs, dirAttr{osPathname, newPath})
+			}
+			return nil
+		},
+		ErrorCallback: func(osPathname string, err error) godirwalk.ErrorAction {
+			addErr(osPathname, err)
+			return godirwalk.SkipNode
+		},
+	})
+	close(jobs)
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+
+	for _, d := range dirs {
+		srcAttr, err := os.Lstat(d.src)
+		if err == nil {
+			err = copyAttributes(d.dst, srcAttr)
+		}
+		if err != nil {
+			copyErr.add(d.src, err)
+		}
+	}
+	if len(copyErr.Failures) > 0 {
+		sort.Slice(copyErr.Failures, func(i, j int) bool {
+			return copyErr.Failures[i].Path < copyErr.Failures[j].Path
+		})
+		return copyErr
+	}
+	return nil
+}
+
+// targetPath maps path under src to the same relative path under dst
+func targetPath(src, dst, path string) (string, error) {
+	rel, err := filepath.Rel(src, path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dst, rel), nil
+}
+
+// DirSize returns the total size of regular files under path
+func DirSize(path string) (int64, error) {
+	var size int64
+	err := godirwalk.Walk(path, &godirwalk.Options{
+		Unsorted: true,
+		Callback: func(osPathname string, de *godirwalk.Dirent) error {
+			if !de.IsRegular() {
+				return nil
+			}
+			fi, err := os.Lstat(osPathname)
+			if err != nil {
+				return err
+			}
+			size += fi.Size()
+			return nil
+		},
+	})
+	return size, err
+}
+
+// FormatBytes formats byte count in human readable unit, e.g. 1.5 MiB
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -1,56 +1,350 @@
-package goup
-
-import (
-	"os"
-	"path/filepath"
-	"testing"
-)
-
-func prepareTestDirStruct(t *testing.T) {
-	t.Helper()
-	err := os.MkdirAll(filepath.FromSlash("test/A"), 0666)
-	if err != nil {
-		t.Error("Cannot create test directory", err)
-	}
-	err = os.MkdirAll(filepath.FromSlash("test/B"), 0666)
-	if err != nil {
-		t.Error("Cannot create test directory", err)
-	}
-	f1, err := os.Create(filepath.FromSlash("test/TestFile1"))
-	if err != nil {
-		t.Error("Cannot create test file", err)
-	}
-	f1.WriteString("HelloWorld1")
-	f1.Close()
-	f2, err := os.Create(filepath.FromSlash("test/A/TestFile2"))
-	if err != nil {
-		t.Error("Cannot create test file", err)
-	}
-	f2.WriteString("HelloWorld2")
-	f2.Close()
-}
-
-func cleanUp(t *testing.T) {
-	t.Helper()
-	os.RemoveAll("test")
-	os.RemoveAll("test2")
-}
-
-func TestCopyDir(t *testing.T) {
-	cleanUp(t)
-	prepareTestDirStruct(t)
-	err := RecursiveCopyDir("test", "test2")
-	if err != nil {
-		t.Error(err)
-	}
-	if _, err := os.Stat(filepath.FromSlash("test2/TestFile1")); err != nil {
-		t.Error("test2/TestFile1 not found")
-	}
-	if _, err := os.Stat(filepath.FromSlash("test2/A/TestFile2")); err != nil {
-		t.Error("test2/A/TestFile2 not found")
-	}
-	if _, err := os.Stat(filepath.FromSlash("test2/B")); err != nil {
-		t.Error("test2/B not found")
-	}
-	cleanUp(t)
-}
+package goup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func prepareTestDirStruct(t *testing.T) {
+	t.Helper()
+	err := os.MkdirAll(filepath.FromSlash("test/A"), 0666)
+	if err != nil {
+		t.Error("Cannot create test directory", err)
+	}
+	err = os.MkdirAll(filepath.FromSlash("test/B"), 0666)
+	if err != nil {
+		t.Error("Cannot create test directory", err)
+	}
+	f1, err := os.Create(filepath.FromSlash("test/TestFile1"))
+	if err != nil {
+		t.Error("Cannot create test file", err)
+	}
+	f1.WriteString("HelloWorld1")
+	f1.Close()
+	f2, err := os.Create(filepath.FromSlash("test/A/TestFile2"))
+	if err != nil {
+		t.Error("Cannot create test file", err)
+	}
+	f2.WriteString("HelloWorld2")
+	f2.Close()
+}
+
+func cleanUp(t *testing.T) {
+	t.Helper()
+	os.RemoveAll("test")
+	os.RemoveAll("test2")
+}
+
+func TestCopyDir(t *testing.T) {
+	cleanUp(t)
+	prepareTestDirStruct(t)
+	err := RecursiveCopyDir("test", "test2")
+	if err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(filepath.FromSlash("test2/TestFile1")); err != nil {
+		t.Error("test2/TestFile1 not found")
+	}
+	if _, err := os.Stat(filepath.FromSlash("test2/A/TestFile2")); err != nil {
+		t.Error("test2/A/TestFile2 not found")
+	}
+	if _, err := os.Stat(filepath.FromSlash("test2/B")); err != nil {
+		t.Error("test2/B not found")
+	}
+	cleanUp(t)
+}
+
+func prepareTestTree(t *testing.T, dir string) time.Time {
+	t.Helper()
+	mtime := time.Date(2018, 12, 13, 20, 3, 52, 0, time.UTC)
+	dirs := []string{"A/B/C", "Empty/Nested", "ReadOnlyDir"}
+	for _, d := range dirs {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.FromSlash(d)), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := []struct {
+		name string
+		mode os.FileMode
+	}{
+		{"TestFile1", 0644},
+		{"A/B/C/TestFile2", 0644},
+		{"A/Exec", 0755},
+		{"A/ReadOnly", 0444},
+		{"ReadOnlyDir/TestFile3", 0600},
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, filepath.FromSlash(f.name))
+		if err := ioutil.WriteFile(path, []byte("Hello "+f.name), f.mode); err != nil {
+			t.Fatal(err)
+		}
+		os.Chmod(path, f.mode)
+		os.Chtimes(path, mtime, mtime)
+	}
+	if err := os.Symlink(filepath.FromSlash("B/C/TestFile2"), filepath.Join(dir, "A", "Link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("NotExist", filepath.Join(dir, "Dangling")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("A", filepath.Join(dir, "DirLink")); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(filepath.Join(dir, "Empty", "Nested"), mtime, mtime)
+	os.Chmod(filepath.Join(dir, "ReadOnlyDir"), 0555)
+	return mtime
+}
+
+func TestRecursiveCopyDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Symbolic links and permission bits are not fully supported on Windows")
+	}
+	dir, err := ioutil.TempDir("", "goup-copy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err == nil && info.IsDir() {
+				os.Chmod(path, 0755)
+			}
+			return nil
+		})
+		os.RemoveAll(dir)
+	}()
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+	mtime := prepareTestTree(t, src)
+	// Existing file in destination is overwritten
+	os.MkdirAll(filepath.Join(dst, "A"), 0755)
+	ioutil.WriteFile(filepath.Join(dst, "A", "ReadOnly"), []byte("Old content"), 0400)
+
+	if err := RecursiveCopyDir(src, dst); err != nil {
+		t.Fatalf("RecursiveCopyDir() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		mode     os.FileMode
+		content  string
+		linkTo   string
+		checkMod bool
+	}{
+		{"TestCase 1", "TestFile1", 0644, "Hello TestFile1", "", true},
+		{"TestCase 2", "A/B/C/TestFile2", 0644, "Hello A/B/C/TestFile2", "", true},
+		{"TestCase 3", "A/Exec", 0755, "Hello A/Exec", "", true},
+		{"TestCase 4", "A/ReadOnly", 0444, "Hello A/ReadOnly", "", true},
+		{"TestCase 5", "ReadOnlyDir", os.ModeDir | 0555, "", "", false},
+		{"TestCase 6", "ReadOnlyDir/TestFile3", 0600, "Hello ReadOnlyDir/TestFile3", "", true},
+		{"TestCase 7", "Empty/Nested", os.ModeDir | 0755, "", "", true},
+		{"TestCase 8", "A/Link", os.ModeSymlink, "", filepath.FromSlash("B/C/TestFile2"), false},
+		{"TestCase 9", "Dangling", os.ModeSymlink, "", "NotExist", false},
+		{"TestCase 10", "DirLink", os.ModeSymlink, "", "A", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dst, filepath.FromSlash(tt.path))
+			fi, err := os.Lstat(path)
+			if err != nil {
+				t.Fatalf("%s not copied: %v", tt.path, err)
+			}
+			if tt.mode&os.ModeSymlink != 0 {
+				if fi.Mode()&os.ModeSymlink == 0 {
+					t.Fatalf("%s is not a symbolic link", tt.path)
+				}
+				if target, _ := os.Readlink(path); target != tt.linkTo {
+					t.Errorf("%s links to %s, want %s", tt.path, target, tt.linkTo)
+				}
+				return
+			}
+			if fi.Mode() != tt.mode {
+				t.Errorf("%s mode = %v, want %v", tt.path, fi.Mode(), tt.mode)
+			}
+			if tt.checkMod && !fi.ModTime().Equal(mtime) {
+				t.Errorf("%s mtime = %v, want %v", tt.path, fi.ModTime(), mtime)
+			}
+			if tt.content != "" {
+				if data, _ := ioutil.ReadFile(path); string(data) != tt.content {
+					t.Errorf("%s content = %q, want %q", tt.path, data, tt.content)
+				}
+			}
+		})
+	}
+}
+
+func TestRecursiveCopyDir_Errors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goup-copy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+	for _, f := range []string{"A/TestFile1", "B/TestFile2", "C/TestFile3"} {
+		os.MkdirAll(filepath.Join(src, filepath.Dir(f)), 0755)
+		ioutil.WriteFile(filepath.Join(src, filepath.FromSlash(f)), []byte(f), 0644)
+	}
+	// Files blocking directories A and C from being created
+	os.MkdirAll(dst, 0755)
+	ioutil.WriteFile(filepath.Join(dst, "A"), nil, 0644)
+	ioutil.WriteFile(filepath.Join(dst, "C"), nil, 0644)
+
+	err = RecursiveCopyDir(src, dst)
+	copyErr, ok := err.(*CopyError)
+	if !ok {
+		t.Fatalf("RecursiveCopyDir() error = %v, want *CopyError", err)
+	}
+	failed := make([]string, 0)
+	for _, f := range copyErr.Failures {
+		failed = append(failed, f.Path)
+	}
+	want := []string{filepath.Join(src, "A"), filepath.Join(src, "C")}
+	if !reflect.DeepEqual(failed, want) {
+		t.Errorf("CopyError.Failures = %v, want %v", failed, want)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dst, "B", "TestFile2")); string(data) != "B/TestFile2" {
+		t.Error("B/TestFile2 not copied after failure")
+	}
+}
+
+func TestRecursiveCopyDirWithOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goup-copy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "src")
+	for i := 0; i < 20; i++ {
+		sub := filepath.Join(src, "D"+strconv.Itoa(i%4), "E"+strconv.Itoa(i%3))
+		os.MkdirAll(sub, 0755)
+		ioutil.WriteFile(filepath.Join(sub, "F"+strconv.Itoa(i)), []byte("Hello "+strconv.Itoa(i)), 0644)
+	}
+
+	tests := []struct {
+		name string
+		opts CopyOptions
+	}{
+		{"TestCase 1", CopyOptions{Workers: 1, Strategy: CopyPlain}},
+		{"TestCase 2", CopyOptions{Workers: 4, Strategy: CopyPlain}},
+		{"TestCase 3", CopyOptions{Workers: 4, Strategy: CopyAuto}},
+		{"TestCase 4", CopyOptions{Workers: 4, Strategy: CopyHardlink}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := filepath.Join(dir, "dst"+tt.name)
+			if err := RecursiveCopyDirWithOptions(src, dst, tt.opts); err != nil {
+				t.Fatalf("RecursiveCopyDirWithOptions() error = %v", err)
+			}
+			for i := 0; i < 20; i++ {
+				rel := filepath.Join("D"+strconv.Itoa(i%4), "E"+strconv.Itoa(i%3), "F"+strconv.Itoa(i))
+				data, err := ioutil.ReadFile(filepath.Join(dst, rel))
+				if err != nil || string(data) != "Hello "+strconv.Itoa(i) {
+					t.Errorf("%s = %q, %v", rel, data, err)
+				}
+				srcInfo, _ := os.Stat(filepath.Join(src, rel))
+				dstInfo, _ := os.Stat(filepath.Join(dst, rel))
+				if linked := os.SameFile(srcInfo, dstInfo); linked != (tt.opts.Strategy == CopyHardlink) {
+					t.Errorf("%s hardlinked = %v", rel, linked)
+				}
+			}
+		})
+	}
+}
+
+// generateBenchTree creates a tree resembling GOROOT in size distribution
+func generateBenchTree(b *testing.B) string {
+	b.Helper()
+	dir, err := ioutil.TempDir("", "goup-bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+	data := make([]byte, 64*1024)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	for d := 0; d < 50; d++ {
+		sub := filepath.Join(dir, "src", "pkg"+strconv.Itoa(d), "internal")
+		if err := os.MkdirAll(sub, 0755); err != nil {
+			b.Fatal(err)
+		}
+		for f := 0; f < 20; f++ {
+			size := (f*7919)%len(data) + 1
+			if err := ioutil.WriteFile(filepath.Join(sub, "file"+strconv.Itoa(f)+".go"), data[:size], 0644); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	return dir
+}
+
+func BenchmarkRecursiveCopyDir(b *testing.B) {
+	dir := generateBenchTree(b)
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "src")
+
+	benchmarks := []struct {
+		name string
+		opts CopyOptions
+	}{
+		{"Plain/1", CopyOptions{Workers: 1, Strategy: CopyPlain}},
+		{"Plain/NumCPU", CopyOptions{Strategy: CopyPlain}},
+		{"Auto/1", CopyOptions{Workers: 1, Strategy: CopyAuto}},
+		{"Auto/NumCPU", CopyOptions{Strategy: CopyAuto}},
+		{"Hardlink/NumCPU", CopyOptions{Strategy: CopyHardlink}},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				dst := filepath.Join(dir, "dst")
+				if err := RecursiveCopyDirWithOptions(src, dst, bm.opts); err != nil {
+					b.Fatal(err)
+				}
+				b.StopTimer()
+				os.RemoveAll(dst)
+				b.StartTimer()
+			}
+		})
+	}
+}
+
+func TestDirSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goup-size")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "a", "b"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "a", "f1"), make([]byte, 100), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "a", "b", "f2"), make([]byte, 28), 0644)
+	os.Symlink("a/f1", filepath.Join(dir, "link"))
+	if got, err := DirSize(dir); err != nil || got != 128 {
+		t.Errorf("DirSize() = %v, %v; want 128", got, err)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int64
+		want string
+	}{
+		{"TestCase 1", 512, "512 B"},
+		{"TestCase 2", 1536, "1.5 KiB"},
+		{"TestCase 3", 250 << 20, "250.0 MiB"},
+		{"TestCase 4", 3 << 30, "3.0 GiB"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatBytes(tt.n); got != tt.want {
+				t.Errorf("FormatBytes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
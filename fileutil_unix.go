@@ -0,0 +1,35 @@
+//go:build !windows
+// +build !windows
+
+package goup
+
+import (
+	"os"
+	"syscall"
+)
+
+// copyOwner sets owner and group of path to those of srcAttr. Lack of
+// permission (e.g. not running as root) is not an error.
+func copyOwner(path string, srcAttr os.FileInfo) error {
+	stat, ok := srcAttr.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	err := os.Lchown(path, int(stat.Uid), int(stat.Gid))
+	if os.IsPermission(err) {
+		return nil
+	}
+	return err
+}
+
+// SameFileSystem tells if existing paths a and b are on the same file system
+func SameFileSystem(a, b string) bool {
+	fa, errA := os.Stat(a)
+	fb, errB := os.Stat(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	sa, okA := fa.Sys().(*syscall.Stat_t)
+	sb, okB := fb.Sys().(*syscall.Stat_t)
+	return okA && okB && sa.Dev == sb.Dev
+}
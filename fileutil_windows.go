@@ -0,0 +1,19 @@
+package goup
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// copyOwner is a no-op as file ownership is not preserved on Windows
+func copyOwner(path string, srcAttr os.FileInfo) error {
+	return nil
+}
+
+// SameFileSystem tells if paths a and b are on the same volume
+func SameFileSystem(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && strings.EqualFold(filepath.VolumeName(absA), filepath.VolumeName(absB))
+}
@@ -0,0 +1,179 @@
+package goup
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// goEnvVars are the variables read by ReadGoEnv
+var goEnvVars = []string{"GOROOT", "GOPATH", "GOBIN", "GOCACHE", "GOMODCACHE", "GOVERSION", "GOOS", "GOARCH", "GOTOOLCHAIN"}
+
+// GoEnv holds the `go env` variables of a Go installation used by goup.
+// GOVERSION and GOTOOLCHAIN are empty for toolchains predating them.
+type GoEnv struct {
+	GOROOT      string
+	GOPATH      string
+	GOBIN       string
+	GOCACHE     string
+	GOMODCACHE  string
+	GOVERSION   string
+	GOOS        string
+	GOARCH      string
+	GOTOOLCHAIN string
+	// Executable is the absolute path of go executable with symbolic links
+	// resolved
+	Executable string
+}
+
+// RootMismatchError is returned by ReadGoEnv when go executable is not in
+// the GOROOT it reports, e.g. GOROOT is set to another installation
+type RootMismatchError struct {
+	GOROOT     string
+	Executable string
+}
+
+func (e *RootMismatchError) Error() string {
+	return fmt.Sprintf("%s does not belong to GOROOT %s, check GOROOT and GOTOOLCHAIN in your environment", e.Executable, e.GOROOT)
+}
+
+// goCommand prepares go subcommand which reports on the installation of
+// exePath itself: it runs outside any module so go.mod toolchain lines do
+// not apply, and with GOTOOLCHAIN=local so no other toolchain is switched to
+func goCommand(exePath string, arg ...string) *exec.Cmd {
+	cmd := exec.Command(exePath, arg...)
+	cmd.Dir = os.TempDir()
+	cmd.Env = append(os.Environ(), "GOTOOLCHAIN=local")
+	return cmd
+}
+
+// ReadGoEnv reads the environment of go executable at exePath (looked up on
+// PATH if it has no directory) with `go env -json`, or by parsing `go env` on
+// toolchains without -json. GOROOT is cross-checked against the executable,
+// in which case env is returned together with *RootMismatchError.
+func ReadGoEnv(exePath string) (GoEnv, error) {
+	exe, err := exec.LookPath(exePath)
+	if err != nil {
+		return GoEnv{}, errors.Wrap(err, "Cannot find go executable")
+	}
+	if exe, err = filepath.Abs(exe); err == nil {
+		exe, err = filepath.EvalSymlinks(exe)
+	}
+	if err != nil {
+		return GoEnv{}, errors.Wrap(err, "Cannot resolve go executable")
+	}
+
+	vars, err := readGoEnvJSON(exe)
+	if err != nil {
+		out, plainErr := goCommand(exe, "env").Output()
+		if plainErr != nil {
+			return GoEnv{}, errors.Wrap(plainErr, "Error happens when trying to execute go env")
+		}
+		vars = parseGoEnv(string(out))
+	}
+	env := GoEnv{
+		GOROOT:      vars["GOROOT"],
+		GOPATH:      vars["GOPATH"],
+		GOBIN:       vars["GOBIN"],
+		GOCACHE:     vars["GOCACHE"],
+		GOMODCACHE:  vars["GOMODCACHE"],
+		GOVERSION:   vars["GOVERSION"],
+		GOOS:        vars["GOOS"],
+		GOARCH:      vars["GOARCH"],
+		GOTOOLCHAIN: vars["GOTOOLCHAIN"],
+		Executable:  exe,
+	}
+	if env.GOROOT == "" {
+		return env, errors.New("GOROOT not found")
+	}
+	if env.GOTOOLCHAIN != "" {
+		// GOTOOLCHAIN=local above hides the configured value
+		cmd := exec.Command(exe, "env", "GOTOOLCHAIN")
+		cmd.Dir = os.TempDir()
+		if out, err := cmd.Output(); err == nil {
+			env.GOTOOLCHAIN = strings.TrimSpace(string(out))
+		}
+	}
+	return env, env.checkRoot()
+}
+
+func readGoEnvJSON(exe string) (map[string]string, error) {
+	out, err := goCommand(exe, append([]string{"env", "-json"}, goEnvVars...)...).Output()
+	if err != nil {
+		return nil, err
+	}
+	vars := make(map[string]string)
+	if err := json.Unmarshal(out, &vars); err != nil {
+		return nil, errors.Wrap(err, "Cannot parse go env output")
+	}
+	return vars, nil
+}
+
+// BinDir returns where go install puts executables: GOBIN, or bin in the
+// first GOPATH entry
+func (env GoEnv) BinDir() string {
+	if env.GOBIN != "" {
+		return env.GOBIN
+	}
+	if list := filepath.SplitList(env.GOPATH); len(list) > 0 {
+		return filepath.Join(list[0], "bin")
+	}
+	return ""
+}
+
+// checkRoot verifies the executable is bin/go under GOROOT
+func (env GoEnv) checkRoot() error {
+	root, err := filepath.EvalSymlinks(env.GOROOT)
+	if err != nil {
+		return &RootMismatchError{env.GOROOT, env.Executable}
+	}
+	want := filepath.Join(root, "bin", filepath.Base(env.Executable))
+	same := want == env.Executable
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		// Case insensitive file systems
+		same = strings.EqualFold(want, env.Executable)
+	}
+	if !same {
+		return &RootMismatchError{env.GOROOT, env.Executable}
+	}
+	return nil
+}
+
+// parseGoEnv parses the output of `go env` without -json, which is shell
+// assignments on Unix (KEY="value" or KEY='value'), `set KEY=value` on
+// Windows and KEY='value' on Plan 9
+func parseGoEnv(out string) map[string]string {
+	vars := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		line = strings.TrimPrefix(line, "set ")
+		i := strings.Index(line, "=")
+		if i <= 0 {
+			continue
+		}
+		vars[line[:i]] = unquoteEnv(line[i+1:])
+	}
+	return vars
+}
+
+// unquoteEnv removes shell quoting of a `go env` value
+func unquoteEnv(value string) string {
+	if len(value) < 2 {
+		return value
+	}
+	switch {
+	case value[0] == '\'' && value[len(value)-1] == '\'':
+		return strings.Replace(value[1:len(value)-1], `'\''`, "'", -1)
+	case value[0] == '"' && value[len(value)-1] == '"':
+		return value[1 : len(value)-1]
+	}
+	return value
+}
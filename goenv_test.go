@@ -0,0 +1,105 @@
+package goup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestParseGoEnv(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		want map[string]string
+	}{
+		{
+			"TestCase 1",
+			"GOARCH=\"amd64\"\nGOROOT_FINAL=\"/opt/go\"\nGOROOT=\"/usr/local/go\"\nGOOS=\"linux\"\n",
+			map[string]string{"GOARCH": "amd64", "GOROOT_FINAL": "/opt/go", "GOROOT": "/usr/local/go", "GOOS": "linux"},
+		}, {
+			"TestCase 2",
+			"GOROOT='/usr/local/go'\nGOFLAGS='-ldflags=-X=main.v=1'\nGOPATH='/home/o'\\''brien/go'\n",
+			map[string]string{"GOROOT": "/usr/local/go", "GOFLAGS": "-ldflags=-X=main.v=1", "GOPATH": "/home/o'brien/go"},
+		}, {
+			"TestCase 3",
+			"set GOARCH=amd64\r\nset GOROOT=C:\\Program Files\\Go\r\nset GOPATH=C:\\Users\\a=b\\go\r\nset GOTOOLCHAIN=\r\n",
+			map[string]string{"GOARCH": "amd64", "GOROOT": "C:\\Program Files\\Go", "GOPATH": "C:\\Users\\a=b\\go", "GOTOOLCHAIN": ""},
+		}, {
+			"TestCase 4",
+			"\nnot a variable\nGOROOT=/usr/lib/go\n",
+			map[string]string{"GOROOT": "/usr/lib/go"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseGoEnv(tt.out); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseGoEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeGo writes a shell script answering `go env -json` with GOROOT root
+func fakeGo(t *testing.T, dir, root string) string {
+	t.Helper()
+	bin := filepath.Join(dir, "bin")
+	if err := os.MkdirAll(bin, 0755); err != nil {
+		t.Fatal(err)
+	}
+	script := "#!/bin/sh\n" +
+		"if [ \"$2\" = \"-json\" ]; then\n" +
+		"  echo '{\"GOROOT\": \"" + root + "\", \"GOPATH\": \"/home/gopher/go\", \"GOVERSION\": \"go1.22.1\", \"GOOS\": \"linux\", \"GOARCH\": \"amd64\", \"GOTOOLCHAIN\": \"'$GOTOOLCHAIN'\"}'\n" +
+		"else\n" +
+		"  echo go1.22.1+auto\n" +
+		"fi\n"
+	exe := filepath.Join(bin, "go")
+	if err := ioutil.WriteFile(exe, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return exe
+}
+
+func TestReadGoEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Shell script as go executable is not supported on Windows")
+	}
+	dir, err := ioutil.TempDir("", "goup-goenv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dir, _ = filepath.EvalSymlinks(dir)
+
+	exe := fakeGo(t, dir, dir)
+	// go on PATH through a symbolic link, like /usr/bin/go on Linux distributions
+	linkDir := filepath.Join(dir, "usrbin")
+	os.MkdirAll(linkDir, 0755)
+	if err := os.Symlink(exe, filepath.Join(linkDir, "go")); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", linkDir)
+
+	env, err := ReadGoEnv("go")
+	if err != nil {
+		t.Fatalf("ReadGoEnv() error = %v", err)
+	}
+	want := GoEnv{GOROOT: dir, GOPATH: "/home/gopher/go", GOVERSION: "go1.22.1", GOOS: "linux", GOARCH: "amd64", GOTOOLCHAIN: "go1.22.1+auto", Executable: exe}
+	if env != want {
+		t.Errorf("ReadGoEnv() = %+v, want %+v", env, want)
+	}
+
+	other := filepath.Join(dir, "other")
+	exe = fakeGo(t, other, dir)
+	if _, err := ReadGoEnv(exe); err == nil {
+		t.Error("ReadGoEnv() of executable outside GOROOT succeeded")
+	} else if _, ok := err.(*RootMismatchError); !ok {
+		t.Errorf("ReadGoEnv() error = %v, want *RootMismatchError", err)
+	}
+	if root, err := GoPath(exe); err != nil || root != dir {
+		t.Errorf("GoPath() = %v, %v, want %v", root, err, dir)
+	}
+}
@@ -0,0 +1,132 @@
+package goup
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// GoVersion is the parsed output of `go version`
+type GoVersion struct {
+	// Version is the release version. For development builds it is the
+	// version being developed if known (e.g. 1.23 for devel go1.23-abc123),
+	// or zero otherwise.
+	Version VersionInfo
+	OS      string
+	Arch    string
+	// Devel is true for development builds, DevelCommit holds their commit
+	Devel       bool
+	DevelCommit string
+	// Experiments lists GOEXPERIMENT flags the toolchain is built with, e.g.
+	// boringcrypto for "X:boringcrypto"
+	Experiments []string
+	// Vendor holds extra build information added by distributors, e.g.
+	// "Red Hat 1.21.6-1.el9" or "gccgo (GCC) 12.2.1"
+	Vendor string
+}
+
+// GoVersionError is returned when `go version` output cannot be parsed
+type GoVersionError struct {
+	Output string
+	Reason string
+}
+
+func (e *GoVersionError) Error() string {
+	return fmt.Sprintf("Cannot parse go version output %q: %s", e.Output, e.Reason)
+}
+
+// ParseGoVersion parses `go version` output, e.g.
+//
+//	go version go1.21.6 (Red Hat 1.21.6-1.el9) linux/amd64
+//	go version go1.22.0 X:boringcrypto linux/amd64
+//	go version devel go1.23-abc123 Tue Jun 4 12:00:00 2024 +0000 linux/amd64
+func ParseGoVersion(out string) (GoVersion, error) {
+	out = strings.TrimSpace(out)
+	fields := strings.Fields(out)
+	if len(fields) < 4 || fields[0] != "go" || fields[1] != "version" {
+		return GoVersion{}, &GoVersionError{out, "not a go version output"}
+	}
+	var gv GoVersion
+	platform := fields[len(fields)-1]
+	i := strings.Index(platform, "/")
+	if i <= 0 || i == len(platform)-1 {
+		return GoVersion{}, &GoVersionError{out, "no OS/arch"}
+	}
+	gv.OS, gv.Arch = platform[:i], platform[i+1:]
+
+	rest := fields[2 : len(fields)-1]
+	if rest[0] == "devel" {
+		gv.Devel = true
+		if len(rest) < 2 {
+			return GoVersion{}, &GoVersionError{out, "no devel version"}
+		}
+		switch ver := rest[1]; {
+		case strings.HasPrefix(ver, "+"):
+			// devel +b7a85e0003 (before Go 1.21)
+			gv.DevelCommit = ver[1:]
+		case strings.HasPrefix(ver, "go"):
+			// devel go1.23-abc123
+			base := ver[2:]
+			if j := strings.Index(base, "-"); j >= 0 {
+				base, gv.DevelCommit = base[:j], base[j+1:]
+			}
+			gv.Version, _ = ExtractVersionInfo(base)
+		default:
+			gv.DevelCommit = ver
+		}
+		// Remaining fields are commit date, except experiments
+		for _, f := range rest[2:] {
+			if strings.HasPrefix(f, "X:") {
+				gv.Experiments = append(gv.Experiments, strings.Split(f[2:], ",")...)
+			}
+		}
+		return gv, nil
+	}
+
+	if !strings.HasPrefix(rest[0], "go") {
+		return GoVersion{}, &GoVersionError{out, "no version"}
+	}
+	// Suffix of vendor builds, e.g. go1.22.0-20240208-RC00 or go1.21.0+auto
+	ver := rest[0][2:]
+	if j := strings.IndexAny(ver, "-+"); j >= 0 {
+		ver = ver[:j]
+	}
+	var err error
+	if gv.Version, err = ExtractVersionInfo(ver); err != nil {
+		return GoVersion{}, &GoVersionError{out, err.Error()}
+	}
+	vendor := make([]string, 0)
+	for _, f := range rest[1:] {
+		if strings.HasPrefix(f, "X:") {
+			gv.Experiments = append(gv.Experiments, strings.Split(f[2:], ",")...)
+		} else {
+			vendor = append(vendor, f)
+		}
+	}
+	gv.Vendor = strings.Join(vendor, " ")
+	if strings.HasPrefix(gv.Vendor, "(") && strings.HasSuffix(gv.Vendor, ")") && strings.Count(gv.Vendor, "(") == 1 {
+		gv.Vendor = gv.Vendor[1 : len(gv.Vendor)-1]
+	}
+	return gv, nil
+}
+
+// LocalGoVersion runs `go version` of go executable at exePath and parses
+// its output
+func LocalGoVersion(exePath string) (GoVersion, error) {
+	// go runs in temporary directory, so relative path must be resolved here
+	exe, err := exec.LookPath(exePath)
+	if err == nil {
+		exe, err = filepath.Abs(exe)
+	}
+	if err != nil {
+		return GoVersion{}, errors.Wrap(err, "Cannot find go executable")
+	}
+	out, err := goCommand(exe, "version").Output()
+	if err != nil {
+		return GoVersion{}, errors.Wrap(err, "Error happens when trying to execute go")
+	}
+	return ParseGoVersion(string(out))
+}
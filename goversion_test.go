@@ -0,0 +1,125 @@
+package goup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestParseGoVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		want    GoVersion
+		wantErr bool
+	}{
+		{
+			"TestCase 1",
+			"go version go1.10.3 linux/amd64\n",
+			GoVersion{Version: VersionInfo{Major: 1, Minor: 10, Build: 3}, OS: "linux", Arch: "amd64"},
+			false,
+		}, {
+			"TestCase 2",
+			"go version go1.21.7 (Red Hat 1.21.7-1.el9) linux/amd64\n",
+			GoVersion{Version: VersionInfo{Major: 1, Minor: 21, Build: 7}, OS: "linux", Arch: "amd64", Vendor: "Red Hat 1.21.7-1.el9"},
+			false,
+		}, {
+			"TestCase 3",
+			"go version go1.22.0 X:boringcrypto linux/amd64\n",
+			GoVersion{Version: VersionInfo{Major: 1, Minor: 22}, OS: "linux", Arch: "amd64", Experiments: []string{"boringcrypto"}},
+			false,
+		}, {
+			"TestCase 4",
+			"go version devel go1.23-9d5b3ffd7a Tue Jun 4 20:03:47 2024 +0000 linux/amd64\n",
+			GoVersion{Version: VersionInfo{Major: 1, Minor: 23}, OS: "linux", Arch: "amd64", Devel: true, DevelCommit: "9d5b3ffd7a"},
+			false,
+		}, {
+			"TestCase 5",
+			"go version devel +b7a85e0003 Tue Jan 29 15:53:00 2019 +0000 linux/amd64\n",
+			GoVersion{OS: "linux", Arch: "amd64", Devel: true, DevelCommit: "b7a85e0003"},
+			false,
+		}, {
+			"TestCase 6",
+			"go version go1.18 gccgo (GCC) 12.2.1 20230201 linux/amd64\n",
+			GoVersion{Version: VersionInfo{Major: 1, Minor: 18}, OS: "linux", Arch: "amd64", Vendor: "gccgo (GCC) 12.2.1 20230201"},
+			false,
+		}, {
+			"TestCase 7",
+			"go version go1.12beta1 darwin/amd64\n",
+			GoVersion{Version: VersionInfo{Major: 1, Minor: 12, Beta: true, BetaVersion: 1}, OS: "darwin", Arch: "amd64"},
+			false,
+		}, {
+			"TestCase 8",
+			"go version go1.21rc2 windows/amd64\r\n",
+			GoVersion{Version: VersionInfo{Major: 1, Minor: 21, RC: true, RCVersion: 2}, OS: "windows", Arch: "amd64"},
+			false,
+		}, {
+			"TestCase 9",
+			"go version go1.22.0-20240208-RC00 cl/605418006 +8f3c5a5e32 X:fieldtrack,boringcrypto linux/amd64\n",
+			GoVersion{Version: VersionInfo{Major: 1, Minor: 22}, OS: "linux", Arch: "amd64", Experiments: []string{"fieldtrack", "boringcrypto"}, Vendor: "cl/605418006 +8f3c5a5e32"},
+			false,
+		}, {
+			"TestCase 10",
+			"go version go1.20.5 X:nocoverageredesign darwin/arm64\n",
+			GoVersion{Version: VersionInfo{Major: 1, Minor: 20, Build: 5}, OS: "darwin", Arch: "arm64", Experiments: []string{"nocoverageredesign"}},
+			false,
+		}, {
+			"TestCase 11",
+			"go version devel go1.23-9d5b3ffd7a Tue Jun 4 20:03:47 2024 +0000 X:rangefunc linux/amd64\n",
+			GoVersion{Version: VersionInfo{Major: 1, Minor: 23}, OS: "linux", Arch: "amd64", Devel: true, DevelCommit: "9d5b3ffd7a", Experiments: []string{"rangefunc"}},
+			false,
+		},
+		{"TestCase 12", "", GoVersion{}, true},
+		{"TestCase 13", "sh: go: command not found\n", GoVersion{}, true},
+		{"TestCase 14", "go version go1.22.0\n", GoVersion{}, true},
+		{"TestCase 15", "go version go1.x linux/amd64\n", GoVersion{}, true},
+		{"TestCase 16", "go version devel linux/amd64\n", GoVersion{}, true},
+		{"TestCase 17", "go version go1.22.0 linux\n", GoVersion{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseGoVersion(tt.out)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseGoVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if _, ok := err.(*GoVersionError); !ok {
+					t.Errorf("ParseGoVersion() error = %T, want *GoVersionError", err)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseGoVersion() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalGoVersionRelativePath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Shell script as go executable is not supported on Windows")
+	}
+	dir, err := ioutil.TempDir("", "goup-goversion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fakeGoVersion(t, filepath.Join(dir, "rel", "bin"), "1.22.1")
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	os.Chdir(dir)
+
+	gv, err := LocalGoVersion(filepath.Join("rel", "bin", "go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gv.Version != mustVersion(t, "1.22.1") {
+		t.Errorf("LocalGoVersion() = %v, want 1.22.1", gv.Version)
+	}
+}
@@ -0,0 +1,19 @@
+package goup
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// HomeDir returns the directory where goup keeps its state, which is
+// $GOUP_HOME if set, or .goup under user's home directory
+func HomeDir() string {
+	if dir := os.Getenv("GOUP_HOME"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), ".goup")
+	}
+	return filepath.Join(home, ".goup")
+}
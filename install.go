@@ -0,0 +1,335 @@
+package goup
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ChecksumExt is appended to download URL for the published SHA-256
+	ChecksumExt = ".sha256"
+)
+
+// ReleaseFileName returns the archive file name of a Go release, e.g.
+// go1.21.1.linux-amd64.tar.gz
+func ReleaseFileName(version VersionInfo, goos, arch string) string {
+	return path.Base(DownloadUrl(version, goos, arch))
+}
+
+// VersionsDir is where goup keeps the Go versions it installs
+func VersionsDir() string {
+	return filepath.Join(HomeDir(), "versions")
+}
+
+// DownloadsDir is where goup caches downloaded release archives
+func DownloadsDir() string {
+	return filepath.Join(HomeDir(), "downloads")
+}
+
+// ManagedRoot returns the installation root of a Go version managed by goup.
+// Toolchains for a platform other than the host are suffixed with it.
+func ManagedRoot(version VersionInfo, goos, arch string) string {
+	name := "go" + releaseName(version)
+	if goos != runtime.GOOS || arch != runtime.GOARCH {
+		name += "." + goos + "-" + arch
+	}
+	return filepath.Join(VersionsDir(), name)
+}
+
+// FetchChecksum downloads the SHA-256 published next to a release archive
+func FetchChecksum(url string) (string, error) {
+	resp, err := httpClient.Get(url + ChecksumExt)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return "", errors.New("Error code: " + strconv.Itoa(resp.StatusCode))
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 || len(fields[0]) != sha256.Size*2 {
+		return "", errors.New("Malformed checksum file")
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
+// FileSHA256 returns hex encoded SHA-256 of file content
+func FileSHA256(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// VerifyFileSHA256 checks file content against hex encoded SHA-256
+func VerifyFileSHA256(file, want string) error {
+	got, err := FileSHA256(file)
+	if err != nil {
+		return err
+	}
+	if got != strings.ToLower(want) {
+		return fmt.Errorf("Checksum mismatch for %s: got %s, want %s", filepath.Base(file), got, want)
+	}
+	return nil
+}
+
+// CopyVerified copies file to a new temporary file, hashing it on the way,
+// and returns the copy if its SHA-256 matches want. It is used when file is
+// writable by a less privileged user, who could otherwise change it between
+// verification and use. Caller removes the copy.
+func CopyVerified(file, want string) (*os.File, error) {
+	src, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	dst, err := ioutil.TempFile("", "goup-verified-")
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	if _, err = io.Copy(io.MultiWriter(dst, h), src); err == nil {
+		if got := hex.EncodeToString(h.Sum(nil)); got != strings.ToLower(want) {
+			err = fmt.Errorf("Checksum mismatch for %s: got %s, want %s", filepath.Base(file), got, want)
+		}
+	}
+	if err != nil {
+		dst.Close()
+		os.Remove(dst.Name())
+		return nil, err
+	}
+	return dst, nil
+}
+
+// exeSuffix returns the suffix of executables on goos
+func exeSuffix(goos string) string {
+	if goos == "windows" {
+		return ".exe"
+	}
+	return ""
+}
+
+// InstallManifest lists the paths, relative to installation root, which a Go
+// installation for goos/arch must have
+func InstallManifest(goos, arch string) []string {
+	return []string{
+		"VERSION",
+		"bin/go" + exeSuffix(goos),
+		"bin/gofmt" + exeSuffix(goos),
+		"pkg/tool/" + goos + "_" + arch + "/compile" + exeSuffix(goos),
+		"pkg/tool/" + goos + "_" + arch + "/link" + exeSuffix(goos),
+		"src/runtime",
+	}
+}
+
+// VerifyInstall checks Go installation at root is version for goos/arch
+// without running it. The VERSION file and the files in InstallManifest are
+// checked, and bin/go must be an executable for goos.
+func VerifyInstall(root string, version VersionInfo, goos, arch string) error {
+	for _, name := range InstallManifest(goos, arch) {
+		if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(name))); err != nil {
+			return errors.Wrapf(err, "%s is missing", name)
+		}
+	}
+	line, err := readRootVersion(root)
+	if err != nil {
+		return err
+	}
+	if line != "go"+releaseName(version) {
+		return fmt.Errorf("Installed version is %s, want go%v", line, version)
+	}
+	want := executableFormat(goos)
+	got, err := fileExecutableFormat(filepath.Join(root, "bin", "go"+exeSuffix(goos)))
+	if err != nil {
+		return err
+	}
+	if want != "" && got != want {
+		return fmt.Errorf("bin/go is a %s executable, want %s for %s", got, want, goos)
+	}
+	return nil
+}
+
+// readRootVersion returns the version line of VERSION file in Go
+// installation root, e.g. go1.22.3
+func readRootVersion(root string) (string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(root, "VERSION"))
+	if err != nil {
+		return "", errors.Wrap(err, "Cannot read VERSION")
+	}
+	// First line is the version, later lines hold build time since Go 1.21
+	return strings.TrimSpace(strings.SplitN(string(data), "\n", 2)[0]), nil
+}
+
+// executableFormat returns the executable format used by goos, or "" if it
+// is not checked
+func executableFormat(goos string) string {
+	switch goos {
+	case "windows":
+		return "PE"
+	case "darwin", "ios":
+		return "Mach-O"
+	case "plan9":
+		return ""
+	default:
+		return "ELF"
+	}
+}
+
+var (
+	elfMagic   = []byte("\x7fELF")
+	peMagic    = []byte("MZ")
+	machoMagic = [][]byte{
+		{0xfe, 0xed, 0xfa, 0xce}, {0xce, 0xfa, 0xed, 0xfe},
+		{0xfe, 0xed, 0xfa, 0xcf}, {0xcf, 0xfa, 0xed, 0xfe},
+	}
+)
+
+// fileExecutableFormat identifies executable format by file header
+func fileExecutableFormat(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return "", errors.Wrapf(err, "Cannot read %s", filepath.Base(file))
+	}
+	switch {
+	case bytes.Equal(header, elfMagic):
+		return "ELF", nil
+	case bytes.HasPrefix(header, peMagic):
+		return "PE", nil
+	}
+	for _, magic := range machoMagic {
+		if bytes.Equal(header, magic) {
+			return "Mach-O", nil
+		}
+	}
+	return "unknown", nil
+}
+
+// DownloadRelease downloads release archive of version for goos/arch into dir
+// and verifies it against the published SHA-256. An archive already in dir is
+// reused if its checksum matches. progress may wrap the response body to
+// report download progress. Returns the path of the archive.
+func DownloadRelease(version VersionInfo, goos, arch, dir string, progress func(totalSize int64, src io.Reader) io.Reader) (string, error) {
+	return downloadVerified(DownloadUrl(version, goos, arch), dir, progress)
+}
+
+// downloadVerified downloads url into dir unless a file with the published
+// SHA-256 is already there, and returns its path
+func downloadVerified(url, dir string, progress func(totalSize int64, src io.Reader) io.Reader) (string, error) {
+	sum, err := FetchChecksum(url)
+	if err != nil {
+		return "", errors.Wrap(err, "Cannot retrieve checksum")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	archive := filepath.Join(dir, path.Base(url))
+	if VerifyFileSHA256(archive, sum) == nil {
+		return archive, nil
+	}
+
+	tmp, err := ioutil.TempFile(dir, path.Base(url)+".*.part")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = DownloadPackage(url, func(totalSize int64, src io.Reader) error {
+		if progress != nil {
+			src = progress(totalSize, src)
+		}
+		_, err := io.Copy(tmp, src)
+		return err
+	})
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "Cannot download "+url)
+	}
+	if err := VerifyFileSHA256(tmp.Name(), sum); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), archive); err != nil {
+		return "", err
+	}
+	return archive, nil
+}
+
+// InstallArchive extracts release archive to a staging directory next to
+// root, verifies it with VerifyInstall and moves it to root, which must not
+// exist. Nothing is executed, so the archive can be for any platform.
+func InstallArchive(archive, root string, version VersionInfo, goos, arch string, progCback func(format string, arg ...interface{})) error {
+	return installStaged(root, version, goos, arch, func(staging string) error {
+		f, err := os.Open(archive)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return ExtractArchive(f, -1, staging, progCback)
+	})
+}
+
+// installStaged calls fill to populate a staging directory next to root,
+// verifies it and moves it to root
+func installStaged(root string, version VersionInfo, goos, arch string, fill func(staging string) error) error {
+	if _, err := os.Lstat(root); err == nil {
+		return fmt.Errorf("%s already exists", root)
+	}
+	if err := os.MkdirAll(filepath.Dir(root), 0755); err != nil {
+		return err
+	}
+	staging, err := NewStagingDir(root)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	if err := fill(staging); err != nil {
+		return err
+	}
+	if err := VerifyInstall(staging, version, goos, arch); err != nil {
+		return errors.Wrap(err, "Installation cannot be verified")
+	}
+	return os.Rename(staging, root)
+}
+
+// NewStagingDir creates an empty directory next to root, on the same file
+// system so it can be renamed to root
+func NewStagingDir(root string) (string, error) {
+	staging, err := ioutil.TempDir(filepath.Dir(root), "."+filepath.Base(root)+".staging-")
+	if err != nil {
+		return "", errors.Wrap(err, "Cannot create staging directory")
+	}
+	// TempDir creates directory with mode 0700
+	if err := os.Chmod(staging, 0755); err != nil {
+		os.Remove(staging)
+		return "", err
+	}
+	return staging, nil
+}
@@ -0,0 +1,197 @@
+package goup
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeGoTree returns archive entries of a minimal Go release for goos/arch
+func fakeGoTree(version, goos, arch string) []testEntry {
+	exe, magic := "", "\x7fELF"
+	switch goos {
+	case "windows":
+		exe, magic = ".exe", "MZ\x90\x00"
+	case "darwin":
+		magic = "\xcf\xfa\xed\xfe"
+	}
+	tool := "go/pkg/tool/" + goos + "_" + arch + "/"
+	return []testEntry{
+		{"go/VERSION", tar.TypeReg, "go" + version + "\ntime 2023-09-06T15:43:02Z\n", ""},
+		{"go/bin/go" + exe, tar.TypeReg, magic + " go", ""},
+		{"go/bin/gofmt" + exe, tar.TypeReg, magic + " gofmt", ""},
+		{tool + "compile" + exe, tar.TypeReg, magic + " compile", ""},
+		{tool + "link" + exe, tar.TypeReg, magic + " link", ""},
+		{"go/src/runtime/", tar.TypeDir, "", ""},
+	}
+}
+
+func TestVerifyInstall(t *testing.T) {
+	tests := []struct {
+		name    string
+		tree    []testEntry
+		version VersionInfo
+		goos    string
+		arch    string
+		wantErr bool
+	}{
+		{"TestCase 1", fakeGoTree("1.21.1", "linux", "arm64"), VersionInfo{Major: 1, Minor: 21, Build: 1}, "linux", "arm64", false},
+		{"TestCase 2", fakeGoTree("1.21.0", "windows", "amd64"), VersionInfo{Major: 1, Minor: 21}, "windows", "amd64", false},
+		{"TestCase 3", fakeGoTree("1.21.1", "linux", "arm64"), VersionInfo{Major: 1, Minor: 21, Build: 2}, "linux", "arm64", true},
+		{"TestCase 4", fakeGoTree("1.21.1", "linux", "arm64"), VersionInfo{Major: 1, Minor: 21, Build: 1}, "linux", "amd64", true},
+		{"TestCase 5", fakeGoTree("1.21.1", "darwin", "arm64"), VersionInfo{Major: 1, Minor: 21, Build: 1}, "darwin", "arm64", false},
+		{"TestCase 6", fakeGoTree("1.21.1", "linux", "amd64")[1:], VersionInfo{Major: 1, Minor: 21, Build: 1}, "linux", "amd64", true},
+		// ELF binaries in a tree claiming to be for Windows
+		{"TestCase 7", append(fakeGoTree("1.21.1", "windows", "amd64"), testEntry{"go/bin/go.exe", tar.TypeReg, "\x7fELF go", ""}), VersionInfo{Major: 1, Minor: 21, Build: 1}, "windows", "amd64", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "goup-install")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			data := makeTarGz(t, tt.tree)
+			if err := ExtractArchive(writeTempArchive(t, data), -1, dir, func(string, ...interface{}) {}); err != nil {
+				t.Fatal(err)
+			}
+			if err := VerifyInstall(dir, tt.version, tt.goos, tt.arch); (err != nil) != tt.wantErr {
+				t.Errorf("VerifyInstall() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInstallArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goup-install")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	archive := filepath.Join(dir, "go1.21.1.windows-amd64.zip")
+	files := make(map[string]string)
+	for _, e := range fakeGoTree("1.21.1", "windows", "amd64") {
+		if e.typeflag == tar.TypeReg {
+			files[e.name] = e.content
+		}
+	}
+	files["go/src/runtime/runtime.go"] = "package runtime"
+	if err := ioutil.WriteFile(archive, makeZip(t, files), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ver := VersionInfo{Major: 1, Minor: 21, Build: 1}
+	root := filepath.Join(dir, "versions", "go1.21.1")
+
+	if err := InstallArchive(archive, root, ver, "linux", "amd64", func(string, ...interface{}) {}); err == nil {
+		t.Error("InstallArchive() of wrong platform succeeded")
+	}
+	if _, err := os.Stat(root); !os.IsNotExist(err) {
+		t.Errorf("Failed install left %s: %v", root, err)
+	}
+	if err := InstallArchive(archive, root, ver, "windows", "amd64", func(string, ...interface{}) {}); err != nil {
+		t.Fatalf("InstallArchive() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "bin", "go.exe")); err != nil {
+		t.Error(err)
+	}
+	if err := InstallArchive(archive, root, ver, "windows", "amd64", func(string, ...interface{}) {}); err == nil {
+		t.Error("InstallArchive() over existing installation succeeded")
+	}
+	leftovers, _ := filepath.Glob(filepath.Join(dir, "versions", ".*staging*"))
+	if len(leftovers) > 0 {
+		t.Errorf("Staging directories left: %v", leftovers)
+	}
+}
+
+func TestFetchChecksum(t *testing.T) {
+	sum := sha256.Sum256([]byte("archive"))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/go1.21.1.linux-amd64.tar.gz.sha256":
+			fmt.Fprint(w, hex.EncodeToString(sum[:]))
+		case "/go1.21.0.linux-amd64.tar.gz.sha256":
+			fmt.Fprint(w, "<html>")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	tests := []struct {
+		name    string
+		url     string
+		want    string
+		wantErr bool
+	}{
+		{"TestCase 1", srv.URL + "/go1.21.1.linux-amd64.tar.gz", hex.EncodeToString(sum[:]), false},
+		{"TestCase 2", srv.URL + "/go1.21.0.linux-amd64.tar.gz", "", true},
+		{"TestCase 3", srv.URL + "/go1.20.0.linux-amd64.tar.gz", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FetchChecksum(tt.url)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("FetchChecksum() = %v, %v; want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestManagedRoot(t *testing.T) {
+	setGoupHome(t, "/goup")
+	ver := VersionInfo{Major: 1, Minor: 21, Build: 1}
+	if got := ManagedRoot(ver, "plan9", "386"); got != filepath.Join("/goup", "versions", "go1.21.1.plan9-386") {
+		t.Errorf("ManagedRoot() = %v", got)
+	}
+	if got := ReleaseFileName(ver, "windows", "arm64"); got != "go1.21.1.windows-arm64.zip" {
+		t.Errorf("ReleaseFileName() = %v", got)
+	}
+}
+
+func TestCopyVerified(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goup-install")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "archive")
+	ioutil.WriteFile(src, []byte("archive"), 0644)
+	sum := sha256.Sum256([]byte("archive"))
+
+	f, err := CopyVerified(src, hex.EncodeToString(sum[:]))
+	if err != nil {
+		t.Fatalf("CopyVerified() error = %v", err)
+	}
+	defer os.Remove(f.Name())
+	f.Close()
+	// Changing source afterwards does not affect the copy
+	ioutil.WriteFile(src, []byte("tampered"), 0644)
+	if data, _ := ioutil.ReadFile(f.Name()); string(data) != "archive" {
+		t.Errorf("Copy = %q, want archive", data)
+	}
+	if _, err := CopyVerified(src, hex.EncodeToString(sum[:])); err == nil {
+		t.Error("CopyVerified() of tampered file succeeded")
+	}
+}
+
+func writeTempArchive(t *testing.T, data []byte) *os.File {
+	t.Helper()
+	f, err := ioutil.TempFile("", "goup-archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		f.Close()
+		os.Remove(f.Name())
+	})
+	if _, err := f.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
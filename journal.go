@@ -0,0 +1,187 @@
+package goup
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// JournalPhase is the step an upgrade is in
+type JournalPhase string
+
+const (
+	// PhaseBackup means backup is being made, installation is intact
+	PhaseBackup JournalPhase = "backup"
+	// PhaseRemove means backup is complete and installation is being removed
+	PhaseRemove JournalPhase = "remove"
+	// PhaseExtract means new version is being extracted to installation root
+	PhaseExtract JournalPhase = "extract"
+	// PhaseVerify means new version is extracted and being verified
+	PhaseVerify JournalPhase = "verify"
+)
+
+// Journal records the progress of an upgrade so that it can be recovered if
+// goup is killed before the upgrade completes
+type Journal struct {
+	Phase       JournalPhase
+	TargetPath  string
+	BackupPath  string
+	FromVersion VersionInfo
+	ToVersion   VersionInfo
+	PID         int
+	StartedAt   time.Time
+	UpdatedAt   time.Time
+
+	file string
+}
+
+// journalDir replaces the default journal directory when set
+var journalDir string
+
+// JournalDir returns the directory holding journals of ongoing upgrades
+func JournalDir() string {
+	if journalDir != "" {
+		return journalDir
+	}
+	return filepath.Join(HomeDir(), "journal")
+}
+
+// SetJournalDir makes journals kept in dir instead of goup home. goup running
+// as root on behalf of another user uses it to share journals with that user.
+func SetJournalDir(dir string) {
+	journalDir = dir
+}
+
+// journalFile returns the journal path of an installation root
+func journalFile(target string) string {
+	abs, err := filepath.Abs(target)
+	if err != nil {
+		abs = target
+	}
+	sum := sha1.Sum([]byte(filepath.Clean(abs)))
+	return filepath.Join(JournalDir(), hex.EncodeToString(sum[:8])+".json")
+}
+
+// NewJournal starts the journal of an upgrade in PhaseBackup
+func NewJournal(target, backup string, from, to VersionInfo) (*Journal, error) {
+	if err := os.MkdirAll(JournalDir(), 0755); err != nil {
+		return nil, errors.Wrap(err, "Cannot create journal directory")
+	}
+	now := time.Now()
+	j := &Journal{
+		TargetPath:  target,
+		BackupPath:  backup,
+		FromVersion: from,
+		ToVersion:   to,
+		PID:         os.Getpid(),
+		StartedAt:   now,
+		file:        journalFile(target),
+	}
+	return j, j.SetPhase(PhaseBackup)
+}
+
+// SetPhase records the upgrade has entered phase. The journal is written to
+// a temporary file and renamed so it is never left half written.
+func (j *Journal) SetPhase(phase JournalPhase) error {
+	j.Phase = phase
+	j.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(j.file), "journal")
+	if err != nil {
+		return errors.Wrap(err, "Cannot write journal")
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		// Readable by the user who asked root to upgrade
+		err = tmp.Chmod(0644)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), j.file)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return errors.Wrap(err, "Cannot write journal")
+	}
+	return nil
+}
+
+// Complete removes the journal after the upgrade finished or is rolled back
+func (j *Journal) Complete() error {
+	err := os.Remove(j.file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// PendingJournals returns journals of upgrades which did not complete
+func PendingJournals() ([]*Journal, error) {
+	files, err := filepath.Glob(filepath.Join(JournalDir(), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	journals := make([]*Journal, 0, len(files))
+	for _, f := range files {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		j := &Journal{file: f}
+		if err := json.Unmarshal(data, j); err != nil {
+			return nil, errors.Wrapf(err, "Cannot parse journal %s", f)
+		}
+		journals = append(journals, j)
+	}
+	return journals, nil
+}
+
+// Recover brings the installation root back to the last consistent state:
+// if the upgrade was interrupted while backing up, the partial backup is
+// discarded, otherwise the backup is restored. The journal is removed when
+// recovery succeeds.
+func (j *Journal) Recover() error {
+	if j.Phase != PhaseBackup {
+		if err := RestoreBackup(j.BackupPath, j.TargetPath); err != nil {
+			return errors.Wrap(err, "Cannot restore backup")
+		}
+	}
+	if err := RemoveBackup(j.BackupPath); err != nil {
+		return errors.Wrap(err, "Cannot remove backup")
+	}
+	return j.Complete()
+}
+
+// RestoreBackup replaces goPath with the backup, which can be a directory or
+// an archive created by CreateBackupArchive
+func RestoreBackup(backupPath, goPath string) error {
+	if _, err := os.Stat(backupPath); err != nil {
+		return err
+	}
+	if IsBackupArchive(backupPath) {
+		return RestoreBackupArchive(backupPath, goPath)
+	}
+	err := os.RemoveAll(goPath)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(goPath, 0755)
+	if err != nil {
+		return err
+	}
+	return RecursiveCopyDir(backupPath, goPath)
+}
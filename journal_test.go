@@ -0,0 +1,84 @@
+package goup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJournal_Recover(t *testing.T) {
+	tests := []struct {
+		name  string
+		phase JournalPhase
+	}{
+		{"TestCase 1", PhaseBackup},
+		{"TestCase 2", PhaseRemove},
+		{"TestCase 3", PhaseExtract},
+		{"TestCase 4", PhaseVerify},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "goup-journal")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			setGoupHome(t, filepath.Join(dir, "home"))
+
+			target := filepath.Join(dir, "go")
+			backup := filepath.Join(dir, "backup")
+			os.MkdirAll(filepath.Join(target, "bin"), 0755)
+			ioutil.WriteFile(filepath.Join(target, "VERSION"), []byte("go1.21.1"), 0644)
+			ioutil.WriteFile(filepath.Join(target, "bin", "go"), []byte("old go"), 0755)
+
+			j, err := NewJournal(target, backup, mustVersion(t, "1.21.1"), mustVersion(t, "1.21.2"))
+			if err != nil {
+				t.Fatalf("NewJournal() error = %v", err)
+			}
+			if err := RecursiveCopyDir(target, backup); err != nil {
+				t.Fatal(err)
+			}
+			if err := j.SetPhase(tt.phase); err != nil {
+				t.Fatalf("SetPhase() error = %v", err)
+			}
+			// Simulate goup being killed in the middle of the phase
+			switch tt.phase {
+			case PhaseRemove:
+				os.Remove(filepath.Join(target, "VERSION"))
+			case PhaseExtract, PhaseVerify:
+				os.RemoveAll(target)
+				os.MkdirAll(filepath.Join(target, "bin"), 0755)
+				ioutil.WriteFile(filepath.Join(target, "bin", "go"), []byte("new go"), 0755)
+			}
+
+			journals, err := PendingJournals()
+			if err != nil {
+				t.Fatalf("PendingJournals() error = %v", err)
+			}
+			if len(journals) != 1 {
+				t.Fatalf("PendingJournals() returns %d journals, want 1", len(journals))
+			}
+			pending := journals[0]
+			if pending.Phase != tt.phase || pending.TargetPath != target || pending.BackupPath != backup || pending.ToVersion != mustVersion(t, "1.21.2") {
+				t.Errorf("Unexpected journal %+v", pending)
+			}
+			if err := pending.Recover(); err != nil {
+				t.Fatalf("Recover() error = %v", err)
+			}
+
+			if data, _ := ioutil.ReadFile(filepath.Join(target, "VERSION")); string(data) != "go1.21.1" {
+				t.Errorf("VERSION = %q after recovery", data)
+			}
+			if data, _ := ioutil.ReadFile(filepath.Join(target, "bin", "go")); string(data) != "old go" {
+				t.Errorf("bin/go = %q after recovery", data)
+			}
+			if _, err := os.Stat(backup); !os.IsNotExist(err) {
+				t.Error("Backup not removed after recovery")
+			}
+			if journals, _ := PendingJournals(); len(journals) != 0 {
+				t.Error("Journal not removed after recovery")
+			}
+		})
+	}
+}
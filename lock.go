@@ -0,0 +1,85 @@
+package goup
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LockPollInterval is the time between attempts when waiting for a lock
+var LockPollInterval = 500 * time.Millisecond
+
+// LockedError is returned when another goup process holds the lock
+type LockedError struct {
+	PID  int
+	Path string
+}
+
+func (e *LockedError) Error() string {
+	if e.PID <= 0 {
+		return "another goup is running"
+	}
+	return fmt.Sprintf("another goup is running (pid %d)", e.PID)
+}
+
+// InstallLock is an advisory lock on a Go installation root
+type InstallLock struct {
+	path string
+	file *os.File
+}
+
+// LockPath returns the lock file of an installation root. It is placed next
+// to the root as the root itself is removed during upgrade.
+func LockPath(root string) string {
+	root = filepath.Clean(root)
+	return filepath.Join(filepath.Dir(root), "."+filepath.Base(root)+".goup.lock")
+}
+
+// LockInstallRoot acquires the lock of installation root. If wait is false,
+// a *LockedError is returned when the lock is held by another process,
+// otherwise it retries until the lock is released, calling onWait (if not
+// nil) before each retry.
+func LockInstallRoot(root string, wait bool, onWait func(*LockedError)) (*InstallLock, error) {
+	path := LockPath(root)
+	for {
+		lock, err := tryLock(path)
+		if err == nil {
+			return lock, nil
+		}
+		lockedErr, ok := err.(*LockedError)
+		if !ok || !wait {
+			return nil, err
+		}
+		if onWait != nil {
+			onWait(lockedErr)
+		}
+		time.Sleep(LockPollInterval)
+	}
+}
+
+// Unlock releases the lock
+func (l *InstallLock) Unlock() error {
+	return unlockFile(l.file)
+}
+
+// Remove releases the lock and deletes the lock file, for an installation
+// root which is removed
+func (l *InstallLock) Remove() error {
+	return removeLockFile(l)
+}
+
+func readLockPID(path string) int {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
@@ -0,0 +1,188 @@
+package goup
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLockInstallRoot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goup-lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "go")
+
+	lock, err := LockInstallRoot(root, false, nil)
+	if err != nil {
+		t.Fatalf("LockInstallRoot() error = %v", err)
+	}
+	_, err = LockInstallRoot(root, false, nil)
+	lockedErr, ok := err.(*LockedError)
+	if !ok {
+		t.Fatalf("LockInstallRoot() error = %v, want *LockedError", err)
+	}
+	if lockedErr.PID != os.Getpid() {
+		t.Errorf("LockedError.PID = %d, want %d", lockedErr.PID, os.Getpid())
+	}
+	if want := fmt.Sprintf("another goup is running (pid %d)", os.Getpid()); lockedErr.Error() != want {
+		t.Errorf("LockedError.Error() = %q, want %q", lockedErr.Error(), want)
+	}
+
+	released := make(chan struct{})
+	go func() {
+		time.Sleep(2 * LockPollInterval)
+		close(released)
+		lock.Unlock()
+	}()
+	waited := false
+	lock2, err := LockInstallRoot(root, true, func(*LockedError) { waited = true })
+	if err != nil {
+		t.Fatalf("LockInstallRoot() with wait error = %v", err)
+	}
+	select {
+	case <-released:
+	default:
+		t.Error("Lock acquired before release")
+	}
+	if !waited {
+		t.Error("onWait not called")
+	}
+	lock2.Unlock()
+}
+
+func TestLockInstallRoot_Exited(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goup-lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "go")
+
+	// Process exiting without unlocking
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHelperUpgradeProcess$")
+	cmd.Env = append(os.Environ(), "GOUP_TEST_UPGRADE_ROOT="+root, "GOUP_TEST_UPGRADE_ID=exit")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	lock, err := LockInstallRoot(root, false, nil)
+	if err != nil {
+		t.Fatalf("LockInstallRoot() error = %v, lock of exited process is kept", err)
+	}
+	lock.Unlock()
+}
+
+func TestInstallLock_Remove(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goup-lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "go")
+
+	lock, err := LockInstallRoot(root, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waiting := make(chan struct{})
+	acquired := make(chan *InstallLock)
+	go func() {
+		var once sync.Once
+		lock, err := LockInstallRoot(root, true, func(*LockedError) { once.Do(func() { close(waiting) }) })
+		if err != nil {
+			t.Error(err)
+		}
+		acquired <- lock
+	}()
+	<-waiting
+	if err := lock.Remove(); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	waiter := <-acquired
+	if waiter == nil {
+		return
+	}
+	defer waiter.Remove()
+	// Waiter must hold the lock file others open, not the removed one
+	if _, err := LockInstallRoot(root, false, nil); err == nil {
+		t.Error("LockInstallRoot() succeeded while lock is held by waiter")
+	}
+}
+
+// TestHelperUpgradeProcess is run as a separate process by
+// TestLockInstallRoot_Concurrent to simulate an upgrade
+func TestHelperUpgradeProcess(t *testing.T) {
+	root := os.Getenv("GOUP_TEST_UPGRADE_ROOT")
+	if root == "" {
+		return
+	}
+	id := os.Getenv("GOUP_TEST_UPGRADE_ID")
+	lock, err := LockInstallRoot(root, true, nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if id == "exit" {
+		os.Exit(0)
+	}
+	defer lock.Unlock()
+
+	if err := os.RemoveAll(root); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	os.MkdirAll(filepath.Join(root, "bin"), 0755)
+	for _, name := range []string{"VERSION", "bin/go", "bin/gofmt"} {
+		ioutil.WriteFile(filepath.Join(root, filepath.FromSlash(name)), []byte(id), 0644)
+		time.Sleep(20 * time.Millisecond)
+	}
+	for _, name := range []string{"VERSION", "bin/go", "bin/gofmt"} {
+		data, err := ioutil.ReadFile(filepath.Join(root, filepath.FromSlash(name)))
+		if err != nil || string(data) != id {
+			fmt.Fprintf(os.Stderr, "%s corrupted by concurrent upgrade: %q, %v\n", name, data, err)
+			os.Exit(1)
+		}
+	}
+	os.Exit(0)
+}
+
+func TestLockInstallRoot_Concurrent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goup-lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	root := filepath.Join(dir, "go")
+
+	cmds := make([]*exec.Cmd, 0)
+	for i := 0; i < 4; i++ {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestHelperUpgradeProcess$")
+		cmd.Env = append(os.Environ(), "GOUP_TEST_UPGRADE_ROOT="+root, "GOUP_TEST_UPGRADE_ID="+strconv.Itoa(i))
+		cmd.Stderr = os.Stderr
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		cmds = append(cmds, cmd)
+	}
+	for i, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Errorf("Upgrade %d failed: %v", i, err)
+		}
+	}
+	data, err := ioutil.ReadFile(filepath.Join(root, "VERSION"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"bin/go", "bin/gofmt"} {
+		if got, _ := ioutil.ReadFile(filepath.Join(root, filepath.FromSlash(name))); string(got) != string(data) {
+			t.Errorf("%s = %q, want %q", name, got, data)
+		}
+	}
+}
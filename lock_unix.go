@@ -0,0 +1,73 @@
+//go:build !windows
+// +build !windows
+
+package goup
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// tryLock locks path with flock(2). The lock is held by the OS until it is
+// released or the process exits, so a lock is never stale.
+func tryLock(path string) (*InstallLock, error) {
+	for {
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, err
+		}
+		err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		switch err {
+		case nil:
+		case syscall.EWOULDBLOCK:
+			f.Close()
+			return nil, &LockedError{PID: readLockPID(path), Path: path}
+		case syscall.ENOLCK, syscall.EOPNOTSUPP, syscall.ENOSYS:
+			f.Close()
+			return nil, errors.Wrapf(err, "File system of %s does not support locking", path)
+		default:
+			f.Close()
+			return nil, err
+		}
+
+		// Lock file removed by its holder before this process locked it
+		// no longer guards anything, open it again
+		held, errHeld := f.Stat()
+		current, errCurrent := os.Stat(path)
+		if errHeld != nil || errCurrent != nil || !os.SameFile(held, current) {
+			unlockFile(f)
+			continue
+		}
+
+		if err = f.Truncate(0); err == nil {
+			_, err = f.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+		}
+		if err != nil {
+			unlockFile(f)
+			return nil, err
+		}
+		return &InstallLock{path: path, file: f}, nil
+	}
+}
+
+// removeLockFile deletes the lock file before releasing it, so no other
+// process can lock it in between. Processes which opened it earlier find it
+// removed once they get the lock and open it again.
+func removeLockFile(l *InstallLock) error {
+	err := os.Remove(l.path)
+	if unlockErr := unlockFile(l.file); err == nil {
+		err = unlockErr
+	}
+	return err
+}
+
+// unlockFile releases the flock. The lock file is kept as other processes
+// may be waiting on it.
+func unlockFile(f *os.File) error {
+	f.Truncate(0)
+	defer f.Close()
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
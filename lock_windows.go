@@ -0,0 +1,54 @@
+package goup
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// errSharingViolation is ERROR_SHARING_VIOLATION, returned when another
+// process has the file open without sharing write access
+const errSharingViolation syscall.Errno = 32
+
+// tryLock opens path for writing without sharing write access with other
+// processes. Windows keeps the file locked until it is closed or the process
+// exits, so a lock is never stale.
+func tryLock(path string) (*InstallLock, error) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	h, err := syscall.CreateFile(name, syscall.GENERIC_READ|syscall.GENERIC_WRITE,
+		syscall.FILE_SHARE_READ, nil, syscall.OPEN_ALWAYS, syscall.FILE_ATTRIBUTE_NORMAL, 0)
+	switch err {
+	case nil:
+	case errSharingViolation:
+		return nil, &LockedError{PID: readLockPID(path), Path: path}
+	default:
+		return nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	f := os.NewFile(uintptr(h), path)
+	if err = f.Truncate(0); err == nil {
+		_, err = f.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &InstallLock{path: path, file: f}, nil
+}
+
+// removeLockFile releases the lock file and deletes it. Windows does not
+// delete a file another process has opened, so a lock taken in between is
+// kept and the error is ignored.
+func removeLockFile(l *InstallLock) error {
+	err := unlockFile(l.file)
+	os.Remove(l.path)
+	return err
+}
+
+// unlockFile closes the lock file, which releases it
+func unlockFile(f *os.File) error {
+	f.Truncate(0)
+	return f.Close()
+}
@@ -0,0 +1,251 @@
+package goup
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Channel decides which kind of releases are considered for upgrade
+type Channel int
+
+const (
+	// ChannelStable considers standard releases only
+	ChannelStable Channel = iota
+	// ChannelRC considers standard releases and release candidates
+	ChannelRC
+	// ChannelBeta considers standard releases, release candidates and betas
+	ChannelBeta
+)
+
+func (c Channel) String() string {
+	switch c {
+	case ChannelRC:
+		return "rc"
+	case ChannelBeta:
+		return "beta"
+	default:
+		return "stable"
+	}
+}
+
+// Accepts tells if a version is available in the channel
+func (c Channel) Accepts(vi VersionInfo) bool {
+	switch {
+	case vi.Beta:
+		return c >= ChannelBeta
+	case vi.RC:
+		return c >= ChannelRC
+	default:
+		return true
+	}
+}
+
+// ParseChannel converts channel name (stable, rc or beta) to Channel
+func ParseChannel(name string) (Channel, error) {
+	switch strings.ToLower(name) {
+	case "", "stable":
+		return ChannelStable, nil
+	case "rc":
+		return ChannelRC, nil
+	case "beta":
+		return ChannelBeta, nil
+	}
+	return ChannelStable, fmt.Errorf("Unknown channel %q", name)
+}
+
+// PolicyKind is the rule used to pick the upgrade target
+type PolicyKind int
+
+const (
+	// PolicyPatch picks the latest patch of the current minor version
+	PolicyPatch PolicyKind = iota
+	// PolicyMinor picks the latest version available
+	PolicyMinor
+	// PolicySupported stays on one of the two supported minor versions
+	PolicySupported
+	// PolicyNMinus1 picks the latest patch of the minor version before the latest
+	PolicyNMinus1
+	// PolicyPinned picks the latest version satisfying a constraint
+	PolicyPinned
+	// PolicySecurity upgrades only when local version is affected by known
+	// vulnerabilities, to the latest patch fixing all of them
+	PolicySecurity
+)
+
+// Policy describes how goup picks the version to upgrade to
+type Policy struct {
+	Kind       PolicyKind
+	Constraint Constraint
+	Channel    Channel
+	// Advisories affecting local version, used by PolicySecurity
+	Advisories []Advisory
+}
+
+func (p Policy) String() string {
+	switch p.Kind {
+	case PolicyMinor:
+		return "minor"
+	case PolicySupported:
+		return "supported"
+	case PolicyNMinus1:
+		return "n-1"
+	case PolicyPinned:
+		return "pinned:" + p.Constraint.String()
+	case PolicySecurity:
+		return "security"
+	default:
+		return "patch"
+	}
+}
+
+// ParsePolicy converts policy name to Policy. Accepted names are patch,
+// minor, supported, n-1, security and pinned:<constraint>. The returned policy uses
+// the stable channel.
+func ParsePolicy(name string) (Policy, error) {
+	lower := strings.ToLower(strings.TrimSpace(name))
+	switch lower {
+	case "", "patch":
+		return Policy{Kind: PolicyPatch}, nil
+	case "minor":
+		return Policy{Kind: PolicyMinor}, nil
+	case "supported":
+		return Policy{Kind: PolicySupported}, nil
+	case "n-1":
+		return Policy{Kind: PolicyNMinus1}, nil
+	case "security":
+		return Policy{Kind: PolicySecurity}, nil
+	}
+	if strings.HasPrefix(lower, "pinned:") {
+		c, err := ParseConstraint(lower[len("pinned:"):])
+		if err != nil {
+			return Policy{}, err
+		}
+		return Policy{Kind: PolicyPinned, Constraint: c}, nil
+	}
+	return Policy{}, fmt.Errorf("Unknown upgrade policy %q", name)
+}
+
+// ErrNoCandidate is returned when no available version satisfies the policy
+var ErrNoCandidate = errors.New("No version available satisfies the upgrade policy")
+
+// SelectVersion picks the version to upgrade to from available versions.
+// The local version is returned when it is already the best choice. Except
+// for pinned policy, a version older than local is never selected.
+func SelectVersion(local VersionInfo, available []VersionInfo, policy Policy) (VersionInfo, error) {
+	candidates := make([]VersionInfo, 0, len(available))
+	for _, v := range available {
+		if policy.Channel.Accepts(v) {
+			candidates = append(candidates, v)
+		}
+	}
+
+	var target VersionInfo
+	var found bool
+	switch policy.Kind {
+	case PolicyPatch:
+		target, found = latest(candidates, func(v VersionInfo) bool {
+			return v.Major == local.Major && v.Minor == local.Minor
+		})
+	case PolicyMinor:
+		target, found = latest(candidates, nil)
+	case PolicySupported:
+		minors := stableMinors(available)
+		if len(minors) == 0 {
+			break
+		}
+		if len(minors) > 2 {
+			minors = minors[:2]
+		}
+		minor := minors[len(minors)-1]
+		for _, m := range minors {
+			if m.Major == local.Major && m.Minor == local.Minor {
+				minor = m
+			}
+		}
+		target, found = latest(candidates, func(v VersionInfo) bool {
+			return v.Major == minor.Major && v.Minor == minor.Minor
+		})
+	case PolicyNMinus1:
+		minors := stableMinors(available)
+		if len(minors) < 2 {
+			break
+		}
+		target, found = latest(candidates, func(v VersionInfo) bool {
+			return v.Major == minors[1].Major && v.Minor == minors[1].Minor
+		})
+	case PolicyPinned:
+		target, found = latest(candidates, policy.Constraint.Match)
+		if !found {
+			return VersionInfo{}, ErrNoCandidate
+		}
+		return target, nil
+	case PolicySecurity:
+		if len(policy.Advisories) == 0 {
+			return local, nil
+		}
+		// Prefer staying on the current minor if it has a fix, otherwise
+		// move to the minor of the minimum fixed version
+		minFixed, _ := MinimumFixedVersion(policy.Advisories)
+		target, found = latest(candidates, func(v VersionInfo) bool {
+			return v.Major == local.Major && v.Minor == local.Minor
+		})
+		if !found || CompareVersion(target, minFixed) < 0 {
+			target, found = latest(candidates, func(v VersionInfo) bool {
+				return v.Major == minFixed.Major && v.Minor == minFixed.Minor && CompareVersion(v, minFixed) >= 0
+			})
+		}
+	}
+
+	if !found {
+		return VersionInfo{}, ErrNoCandidate
+	}
+	if CompareVersion(target, local) < 0 {
+		return local, nil
+	}
+	return target, nil
+}
+
+// latest returns the newest version accepted by filter
+func latest(versions []VersionInfo, filter func(VersionInfo) bool) (VersionInfo, bool) {
+	var best VersionInfo
+	found := false
+	for _, v := range versions {
+		if filter != nil && !filter(v) {
+			continue
+		}
+		if !found || CompareVersion(v, best) > 0 {
+			best = v
+			found = true
+		}
+	}
+	return best, found
+}
+
+// stableMinors returns the distinct Major.Minor having a standard release,
+// newest first
+func stableMinors(versions []VersionInfo) []VersionInfo {
+	minors := make([]VersionInfo, 0)
+	for _, v := range versions {
+		if v.Beta || v.RC {
+			continue
+		}
+		m := VersionInfo{Major: v.Major, Minor: v.Minor}
+		dup := false
+		for _, e := range minors {
+			if e == m {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			minors = append(minors, m)
+		}
+	}
+	sort.Slice(minors, func(i, j int) bool {
+		return CompareVersion(minors[i], minors[j]) > 0
+	})
+	return minors
+}
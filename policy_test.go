@@ -0,0 +1,130 @@
+package goup
+
+import (
+	"testing"
+)
+
+func mustVersion(t *testing.T, s string) VersionInfo {
+	t.Helper()
+	vi, err := ExtractVersionInfo(s)
+	if err != nil {
+		t.Fatalf("Cannot parse version %s: %v", s, err)
+	}
+	return vi
+}
+
+func TestSelectVersion(t *testing.T) {
+	available := []string{
+		"1.23rc1", "1.22.3", "1.22.2", "1.22.1", "1.22", "1.22rc2",
+		"1.21.9", "1.21.8", "1.21", "1.20.14", "1.20.13", "1.19.13", "1.23beta1",
+	}
+	tests := []struct {
+		name    string
+		local   string
+		policy  string
+		channel Channel
+		want    string
+		wantErr bool
+	}{
+		{"TestCase 1", "1.21.8", "patch", ChannelStable, "1.21.9", false},
+		{"TestCase 2", "1.21.8", "minor", ChannelStable, "1.22.3", false},
+		{"TestCase 3", "1.21.8", "minor", ChannelRC, "1.23rc1", false},
+		{"TestCase 4", "1.21.8", "minor", ChannelBeta, "1.23rc1", false},
+		{"TestCase 5", "1.21.8", "supported", ChannelStable, "1.21.9", false},
+		{"TestCase 6", "1.19.5", "supported", ChannelStable, "1.21.9", false},
+		{"TestCase 7", "1.20.1", "n-1", ChannelStable, "1.21.9", false},
+		{"TestCase 8", "1.22.1", "n-1", ChannelStable, "1.22.1", false},
+		{"TestCase 9", "1.22.3", "patch", ChannelStable, "1.22.3", false},
+		{"TestCase 10", "1.22.3", "pinned:1.21", ChannelStable, "1.21.9", false},
+		{"TestCase 11", "1.19.1", "pinned:>=1.20,<1.22", ChannelStable, "1.21.9", false},
+		{"TestCase 12", "1.19.1", "pinned:~1.20.13", ChannelStable, "1.20.14", false},
+		{"TestCase 13", "1.19.1", "pinned:1.22.1", ChannelStable, "1.22.1", false},
+		{"TestCase 14", "1.19.1", "pinned:1.24", ChannelStable, "", true},
+		{"TestCase 15", "1.18.1", "patch", ChannelStable, "", true},
+	}
+	avail := make([]VersionInfo, 0, len(available))
+	for _, v := range available {
+		avail = append(avail, mustVersion(t, v))
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := ParsePolicy(tt.policy)
+			if err != nil {
+				t.Fatalf("ParsePolicy() error = %v", err)
+			}
+			policy.Channel = tt.channel
+			got, err := SelectVersion(mustVersion(t, tt.local), avail, policy)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SelectVersion() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if want := mustVersion(t, tt.want); got != want {
+				t.Errorf("SelectVersion() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    string
+		want    string
+		wantErr bool
+	}{
+		{"TestCase 1", "", "patch", false},
+		{"TestCase 2", "Minor", "minor", false},
+		{"TestCase 3", "n-1", "n-1", false},
+		{"TestCase 4", "pinned:>=1.20", "pinned:>=1.20", false},
+		{"TestCase 5", "pinned:", "", true},
+		{"TestCase 6", "latest", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePolicy(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParsePolicy() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got.String() != tt.want {
+				t.Errorf("ParsePolicy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectVersion_Security(t *testing.T) {
+	entries, err := LoadVulnDB(testVulnDB)
+	if err != nil {
+		t.Fatalf("LoadVulnDB() error = %v", err)
+	}
+	available := []VersionInfo{}
+	for _, v := range []string{"1.22.5", "1.22.4", "1.22.3", "1.21.12", "1.21.11", "1.21.10", "1.20.14"} {
+		available = append(available, mustVersion(t, v))
+	}
+	tests := []struct {
+		name  string
+		local string
+		want  string
+	}{
+		{"TestCase 1", "1.21.10", "1.21.12"},
+		{"TestCase 2", "1.22.5", "1.22.5"},
+		{"TestCase 3", "1.20.14", "1.21.12"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local := mustVersion(t, tt.local)
+			policy := Policy{Kind: PolicySecurity, Advisories: AffectingAdvisories(entries, local)}
+			got, err := SelectVersion(local, available, policy)
+			if err != nil {
+				t.Fatalf("SelectVersion() error = %v", err)
+			}
+			if want := mustVersion(t, tt.want); got != want {
+				t.Errorf("SelectVersion() = %v, want %v", got, want)
+			}
+		})
+	}
+}
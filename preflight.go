@@ -0,0 +1,169 @@
+package goup
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var errDiskSpaceUnsupported = errors.New("Free space cannot be determined on this platform")
+
+// PreflightCheck describes the disk usage of an upgrade or install, checked
+// by Preflight before anything is changed
+type PreflightCheck struct {
+	// GoRoot is the Go installation replaced, or the directory to install to
+	GoRoot string
+	// TempDir holds the downloaded archive and backup, os.TempDir() if empty
+	TempDir string
+	// DownloadSize is the size of archive saved in TempDir, 0 when streaming
+	DownloadSize int64
+	// InstallSize is the estimated size of the new installation, created
+	// next to GoRoot
+	InstallSize int64
+	// BackupSize is the estimated size of backup created in BackupDir
+	BackupSize int64
+	// BackupDir is where backup is created, TempDir if empty
+	BackupDir string
+}
+
+// PreflightError lists every problem found by Preflight
+type PreflightError struct {
+	Problems []string
+}
+
+func (e *PreflightError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("Preflight check failed:")
+	for _, p := range e.Problems {
+		fmt.Fprintf(&sb, "\n  - %s", p)
+	}
+	return sb.String()
+}
+
+// Preflight checks that GoRoot and its parent are writable, that TempDir is
+// usable and that file systems have enough free space for the download,
+// new installation and backup. Problems are returned as *PreflightError.
+func Preflight(c PreflightCheck) error {
+	if c.TempDir == "" {
+		c.TempDir = os.TempDir()
+	}
+	perr := &PreflightError{}
+
+	writeDirs := installDirs(c.GoRoot)
+	parent := writeDirs[0]
+	for _, dir := range writeDirs {
+		if err := checkWritable(dir); err != nil {
+			perr.Problems = append(perr.Problems, fmt.Sprintf(
+				"Cannot write to %s (%v). Run goup as a user who can write to it, e.g. with sudo, or choose a Go installation you own", dir, err))
+		}
+	}
+	tempUsable := true
+	if err := checkWritable(c.TempDir); err != nil {
+		tempUsable = false
+		perr.Problems = append(perr.Problems, fmt.Sprintf(
+			"Temporary directory %s is not usable (%v). Set TMPDIR (TMP on Windows) to a writable directory", c.TempDir, err))
+	}
+
+	// Sum up space needed on each file system
+	type need struct {
+		dir     string
+		bytes   int64
+		purpose []string
+	}
+	needs := make([]*need, 0, 2)
+	addNeed := func(dir string, bytes int64, purpose string) {
+		if bytes <= 0 {
+			return
+		}
+		for _, n := range needs {
+			if SameFileSystem(n.dir, dir) {
+				n.bytes += bytes
+				n.purpose = append(n.purpose, purpose)
+				return
+			}
+		}
+		needs = append(needs, &need{dir, bytes, []string{purpose}})
+	}
+	addNeed(parent, c.InstallSize, "new installation")
+	if tempUsable {
+		addNeed(c.TempDir, c.DownloadSize, "download")
+	}
+	switch {
+	case c.BackupDir != "":
+		addNeed(existingAncestor(c.BackupDir), c.BackupSize, "backup")
+	case tempUsable:
+		addNeed(c.TempDir, c.BackupSize, "backup")
+	}
+	for _, n := range needs {
+		free, err := freeSpace(n.dir)
+		if err != nil {
+			// Unknown free space is not a reason to stop
+			continue
+		}
+		if uint64(n.bytes) > free {
+			perr.Problems = append(perr.Problems, fmt.Sprintf(
+				"Not enough space on the file system of %s: %s needed for %s, %s available. Free up space or set TMPDIR to a larger file system",
+				n.dir, FormatBytes(n.bytes), strings.Join(n.purpose, " and "), FormatBytes(int64(free))))
+		}
+	}
+
+	if len(perr.Problems) > 0 {
+		return perr
+	}
+	return nil
+}
+
+// checkWritable creates and removes a file in dir
+func checkWritable(dir string) error {
+	f, err := ioutil.TempFile(dir, ".goup-preflight-")
+	if err != nil {
+		if pe, ok := err.(*os.PathError); ok {
+			return pe.Err
+		}
+		return err
+	}
+	_, err = f.Write([]byte("goup"))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	os.Remove(f.Name())
+	return err
+}
+
+// existingAncestor returns path or its nearest ancestor which exists
+func existingAncestor(path string) string {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
+
+func isDir(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && fi.IsDir()
+}
+
+// RemoteSize returns the size of file at url reported by HTTP HEAD, or -1 if
+// unknown
+func RemoteSize(url string) (int64, error) {
+	resp, err := httpClient.Head(url)
+	if err != nil {
+		return -1, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return -1, errors.New("Error code: " + strconv.Itoa(resp.StatusCode))
+	}
+	return resp.ContentLength, nil
+}
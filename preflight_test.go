@@ -0,0 +1,65 @@
+package goup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestPreflight(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goup-preflight")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	goRoot := filepath.Join(dir, "go")
+	if err := os.MkdirAll(filepath.Join(goRoot, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	readOnly := filepath.Join(dir, "readonly")
+	if err := os.MkdirAll(filepath.Join(readOnly, "go"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.Chmod(readOnly, 0555)
+	defer os.Chmod(readOnly, 0755)
+	// Permission is not enforced for root, nor by Chmod on Windows
+	canWriteReadOnly := checkWritable(readOnly) == nil
+
+	tests := []struct {
+		name    string
+		check   PreflightCheck
+		wantErr string
+		skip    bool
+	}{
+		{"TestCase 1", PreflightCheck{GoRoot: goRoot, TempDir: dir, DownloadSize: 1024, InstallSize: 4096, BackupSize: 1024}, "", false},
+		{"TestCase 2", PreflightCheck{GoRoot: filepath.Join(dir, "versions", "go1.21.1"), TempDir: dir, InstallSize: 4096}, "", false},
+		{"TestCase 3", PreflightCheck{GoRoot: goRoot, TempDir: filepath.Join(dir, "missing")}, "Temporary directory", false},
+		{"TestCase 4", PreflightCheck{GoRoot: goRoot, TempDir: dir, InstallSize: 1 << 62}, "Not enough space", runtime.GOOS != "linux" && runtime.GOOS != "darwin" && runtime.GOOS != "windows"},
+		{"TestCase 5", PreflightCheck{GoRoot: goRoot, TempDir: dir, DownloadSize: 1 << 61, BackupSize: 1 << 61}, "download and backup", runtime.GOOS != "linux" && runtime.GOOS != "darwin" && runtime.GOOS != "windows"},
+		{"TestCase 6", PreflightCheck{GoRoot: filepath.Join(readOnly, "go"), TempDir: dir}, "Cannot write to " + readOnly, canWriteReadOnly},
+		{"TestCase 7", PreflightCheck{GoRoot: goRoot, TempDir: dir, InstallSize: 1 << 61, BackupSize: 1 << 61, BackupDir: dir}, "new installation and backup", runtime.GOOS != "linux" && runtime.GOOS != "darwin" && runtime.GOOS != "windows"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.skip {
+				t.Skip("Not applicable on this platform or user")
+			}
+			err := Preflight(tt.check)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Preflight() error = %v", err)
+				}
+				return
+			}
+			if _, ok := err.(*PreflightError); !ok || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Preflight() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(goRoot, ".goup-preflight-*")); len(leftovers) > 0 {
+		t.Errorf("Preflight left files: %v", leftovers)
+	}
+}
@@ -0,0 +1,68 @@
+package goup
+
+import (
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	"github.com/karrick/godirwalk"
+	"github.com/pkg/errors"
+)
+
+// elevators are the commands used to run goup with elevated privileges, in
+// order of preference
+var elevators = []string{"sudo", "doas"}
+
+// CanModify tells if current user can replace Go installation at root, which
+// requires write access to its parent and to every directory under root as
+// the whole tree is removed before new version is extracted
+func CanModify(root string) bool {
+	if checkWritable(existingAncestor(filepath.Dir(filepath.Clean(root)))) != nil {
+		return false
+	}
+	if !isDir(root) {
+		return true
+	}
+	err := godirwalk.Walk(root, &godirwalk.Options{
+		Unsorted: true,
+		Callback: func(osPathname string, de *godirwalk.Dirent) error {
+			if !de.IsDir() {
+				return nil
+			}
+			return checkWritable(osPathname)
+		},
+	})
+	return err == nil
+}
+
+// installDirs lists the directories written when root is replaced or created
+func installDirs(root string) []string {
+	dirs := []string{existingAncestor(filepath.Dir(filepath.Clean(root)))}
+	if isDir(root) {
+		dirs = append(dirs, root)
+		if bin := filepath.Join(root, "bin"); isDir(bin) {
+			dirs = append(dirs, bin)
+		}
+	}
+	return dirs
+}
+
+// UserGoRoot is the per-user Go installation used when the system one cannot
+// be modified by current user
+func UserGoRoot() string {
+	return filepath.Join(HomeDir(), "go")
+}
+
+// ElevateCommand returns the path of sudo or doas, whichever is found first
+// on PATH
+func ElevateCommand() (string, error) {
+	if runtime.GOOS == "windows" {
+		return "", errors.New("Running with elevated privileges is not supported on Windows, use an administrator console instead")
+	}
+	for _, name := range elevators {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	return "", errors.New("Neither sudo nor doas is found on PATH")
+}
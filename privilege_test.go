@@ -0,0 +1,67 @@
+package goup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCanModify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goup-privilege")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "go", "bin"), 0755)
+	os.MkdirAll(filepath.Join(dir, "readonly", "go", "bin"), 0755)
+	os.Chmod(filepath.Join(dir, "readonly", "go", "bin"), 0555)
+	defer os.Chmod(filepath.Join(dir, "readonly", "go", "bin"), 0755)
+	os.MkdirAll(filepath.Join(dir, "nested", "go", "src", "internal"), 0755)
+	os.Chmod(filepath.Join(dir, "nested", "go", "src", "internal"), 0555)
+	defer os.Chmod(filepath.Join(dir, "nested", "go", "src", "internal"), 0755)
+
+	if !CanModify(filepath.Join(dir, "go")) {
+		t.Error("CanModify() = false for writable installation")
+	}
+	if !CanModify(filepath.Join(dir, "new", "go")) {
+		t.Error("CanModify() = false for new installation in writable directory")
+	}
+	if checkWritable(filepath.Join(dir, "readonly", "go", "bin")) == nil {
+		t.Skip("Permission is not enforced for this user")
+	}
+	if CanModify(filepath.Join(dir, "readonly", "go")) {
+		t.Error("CanModify() = true for read-only bin directory")
+	}
+	if CanModify(filepath.Join(dir, "nested", "go")) {
+		t.Error("CanModify() = true for read-only directory deep in installation")
+	}
+}
+
+func TestElevateCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		if _, err := ElevateCommand(); err == nil {
+			t.Error("ElevateCommand() succeeded on Windows")
+		}
+		return
+	}
+	dir, err := ioutil.TempDir("", "goup-privilege")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+
+	os.Setenv("PATH", dir)
+	if _, err := ElevateCommand(); err == nil {
+		t.Error("ElevateCommand() succeeded without sudo or doas")
+	}
+	doas := filepath.Join(dir, "doas")
+	if err := ioutil.WriteFile(doas, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ElevateCommand(); err != nil || got != doas {
+		t.Errorf("ElevateCommand() = %v, %v; want %v", got, err, doas)
+	}
+}
@@ -0,0 +1,124 @@
+package goup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ManagedInstall is a Go version goup installed in VersionsDir
+type ManagedInstall struct {
+	Version VersionInfo
+	GOOS    string
+	Arch    string
+	Root    string
+}
+
+// ManagedInstalls lists Go versions of every platform installed in
+// VersionsDir, newest first
+func ManagedInstalls() ([]ManagedInstall, error) {
+	entries, err := ioutil.ReadDir(VersionsDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	installs := make([]ManagedInstall, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() || !strings.HasPrefix(e.Name(), "go") {
+			continue
+		}
+		// Toolchains for other platforms have .os-arch suffix
+		name, goos, arch := e.Name()[2:], runtime.GOOS, runtime.GOARCH
+		if i := strings.LastIndex(name, "."); i > 0 {
+			if platform := strings.SplitN(name[i+1:], "-", 2); len(platform) == 2 {
+				name, goos, arch = name[:i], platform[0], platform[1]
+			}
+		}
+		v, err := ExtractVersionInfo(name)
+		root := filepath.Join(VersionsDir(), e.Name())
+		if err != nil || ManagedRoot(v, goos, arch) != root {
+			continue
+		}
+		installs = append(installs, ManagedInstall{v, goos, arch, root})
+	}
+	sort.SliceStable(installs, func(i, j int) bool {
+		return CompareVersion(installs[i].Version, installs[j].Version) > 0
+	})
+	return installs, nil
+}
+
+// RemoveInstall deletes a managed installation, returning its size. It fails
+// with *LockedError if goup is installing to it.
+func RemoveInstall(inst ManagedInstall) (int64, error) {
+	lock, err := LockInstallRoot(inst.Root, false, nil)
+	if err != nil {
+		return 0, err
+	}
+	size, _ := DirSize(inst.Root)
+	err = os.RemoveAll(inst.Root)
+	lock.Remove()
+	if err != nil {
+		return 0, errors.Wrapf(err, "Cannot remove %s", inst.Root)
+	}
+	return size, nil
+}
+
+// VersionArtifacts lists downloaded archives of inst in DownloadsDir and
+// hardlinked backups of it next to its root, which are not needed once it is
+// removed. Backups of pending upgrades are not listed, nor are backups in
+// temporary directory as they could be of any Go installation.
+func VersionArtifacts(inst ManagedInstall) ([]CleanItem, error) {
+	journals, err := PendingJournals()
+	if err != nil {
+		return nil, err
+	}
+	inUse := make(map[string]bool)
+	for _, j := range journals {
+		inUse[filepath.Clean(j.BackupPath)] = true
+	}
+
+	items := make([]CleanItem, 0)
+	scan := func(dir, prefix string, kind CleanKind, match func(path string) bool) error {
+		entries, err := ioutil.ReadDir(dir)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for _, fi := range entries {
+			path := filepath.Join(dir, fi.Name())
+			if !strings.HasPrefix(fi.Name(), prefix) || inUse[path] || !match(path) {
+				continue
+			}
+			size := fi.Size()
+			if fi.IsDir() {
+				size, _ = DirSize(path)
+			}
+			items = append(items, CleanItem{path, kind, size, fi.ModTime()})
+		}
+		return nil
+	}
+	// Release archives are named go<version>.<os>-<arch>.<ext>
+	prefix := "go" + releaseName(inst.Version) + "." + inst.GOOS + "-" + inst.Arch + "."
+	if err := scan(DownloadsDir(), prefix, CleanDownload, func(string) bool { return true }); err != nil {
+		return nil, err
+	}
+	// Hardlinked backups are created next to the installation, where the
+	// same version for other platforms may be backed up too
+	tools := filepath.Join("pkg", "tool", inst.GOOS+"_"+inst.Arch)
+	isBackupOf := func(path string) bool {
+		return isDir(filepath.Join(path, tools))
+	}
+	if err := scan(filepath.Dir(inst.Root), ".gobackup-"+inst.Version.String()+"-", CleanBackup, isBackupOf); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
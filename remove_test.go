@@ -0,0 +1,130 @@
+package goup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestManagedInstalls(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goup-remove")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	setGoupHome(t, dir)
+
+	if installs, err := ManagedInstalls(); err != nil || len(installs) != 0 {
+		t.Errorf("ManagedInstalls() without versions directory = %v, %v", installs, err)
+	}
+	for _, name := range []string{"go1.21.5", "go1.22rc1", "go1.22.1.linux-arm64", ".go1.22.2.staging-1", "go1.x", "gopher"} {
+		os.MkdirAll(filepath.Join(VersionsDir(), name), 0755)
+	}
+	installs, err := ManagedInstalls()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ManagedInstall{
+		{mustVersion(t, "1.22.1"), "linux", "arm64", ManagedRoot(mustVersion(t, "1.22.1"), "linux", "arm64")},
+		{mustVersion(t, "1.22rc1"), runtime.GOOS, runtime.GOARCH, filepath.Join(VersionsDir(), "go1.22rc1")},
+		{mustVersion(t, "1.21.5"), runtime.GOOS, runtime.GOARCH, filepath.Join(VersionsDir(), "go1.21.5")},
+	}
+	if runtime.GOOS == "linux" && runtime.GOARCH == "arm64" {
+		t.Skip("Test platform suffix is the host platform")
+	}
+	if len(installs) != len(want) {
+		t.Fatalf("ManagedInstalls() = %+v, want %+v", installs, want)
+	}
+	for i := range want {
+		if installs[i] != want[i] {
+			t.Errorf("ManagedInstalls()[%d] = %+v, want %+v", i, installs[i], want[i])
+		}
+	}
+}
+
+func TestRemoveInstall(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goup-remove")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	setGoupHome(t, filepath.Join(dir, "home"))
+	ver := mustVersion(t, "1.21.5")
+	root := ManagedRoot(ver, "linux", "amd64")
+	tools := filepath.Join("pkg", "tool", "linux_amd64", "compile")
+
+	files := []string{
+		filepath.Join(root, "VERSION"),
+		filepath.Join(root, "bin", "go"),
+		filepath.Join(DownloadsDir(), ReleaseFileName(ver, "linux", "amd64")),
+		filepath.Join(DownloadsDir(), ReleaseFileName(ver, "windows", "amd64")),
+		filepath.Join(DownloadsDir(), ReleaseFileName(mustVersion(t, "1.21.50"), "linux", "amd64")),
+		filepath.Join(VersionsDir(), ".gobackup-1.21.5-1", tools),
+		filepath.Join(VersionsDir(), ".gobackup-1.21.5-2", tools),
+		filepath.Join(VersionsDir(), ".gobackup-1.21.5-3", "pkg", "tool", "windows_amd64", "compile.exe"),
+		filepath.Join(VersionsDir(), ".gobackup-1.21.50-4", tools),
+	}
+	for _, f := range files {
+		os.MkdirAll(filepath.Dir(f), 0755)
+		if err := ioutil.WriteFile(f, []byte("1234"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := NewJournal(filepath.Join(dir, "go"), filepath.Join(VersionsDir(), ".gobackup-1.21.5-2"), ver, mustVersion(t, "1.21.6")); err != nil {
+		t.Fatal(err)
+	}
+
+	inst := ManagedInstall{ver, "linux", "amd64", root}
+	size, err := RemoveInstall(inst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 8 {
+		t.Errorf("RemoveInstall() = %d, want 8", size)
+	}
+	if _, err := os.Stat(root); !os.IsNotExist(err) {
+		t.Error("Installation is not removed")
+	}
+	if _, err := os.Stat(LockPath(root)); !os.IsNotExist(err) {
+		t.Error("Lock file is not removed")
+	}
+
+	items, err := VersionArtifacts(inst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]CleanKind{
+		files[2]: CleanDownload,
+		filepath.Join(VersionsDir(), ".gobackup-1.21.5-1"): CleanBackup,
+	}
+	if len(items) != len(want) {
+		t.Errorf("VersionArtifacts() = %+v", items)
+	}
+	for _, item := range items {
+		if want[item.Path] != item.Kind {
+			t.Errorf("VersionArtifacts() %s = %q, want %q", item.Path, item.Kind, want[item.Path])
+		}
+	}
+}
+
+func TestClearDefaultVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goup-remove")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	setGoupHome(t, dir)
+
+	if err := ClearDefaultVersion(); err != nil {
+		t.Errorf("ClearDefaultVersion() without default error = %v", err)
+	}
+	SetDefaultVersion("1.22")
+	if err := ClearDefaultVersion(); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := DefaultVersion(); v != "" || err != nil {
+		t.Errorf("DefaultVersion() after clearing = %q, %v", v, err)
+	}
+}
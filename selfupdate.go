@@ -0,0 +1,210 @@
+package goup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// SelfUpdateIndexEnv names the environment variable holding the URL of the
+// goup release index
+const SelfUpdateIndexEnv = "GOUP_UPDATE_INDEX"
+
+// SelfIndex lists goup releases for goup self-update, e.g.
+//
+//	{"releases": [{"version": "1.3.0", "files": [
+//	  {"os": "linux", "arch": "amd64", "url": "goup-1.3.0-linux-amd64", "sha256": "..."}]}]}
+//
+// File URLs may be relative to the index, so a mirror only has to copy the
+// directory holding it.
+type SelfIndex struct {
+	Releases []SelfRelease `json:"releases"`
+}
+
+// SelfRelease is a goup release in SelfIndex
+type SelfRelease struct {
+	Version string            `json:"version"`
+	Files   []SelfReleaseFile `json:"files"`
+}
+
+// SelfReleaseFile is the goup executable of a release for one platform
+type SelfReleaseFile struct {
+	OS     string `json:"os"`
+	Arch   string `json:"arch"`
+	URL    string `json:"url"`
+	SHA256 string `json:"sha256"`
+}
+
+// FetchSelfIndex reads the release index from an HTTP(S) URL, a file:// URL
+// or a local path
+func FetchSelfIndex(source string) (SelfIndex, error) {
+	var index SelfIndex
+	var err error
+	if isHTTPURL(source) {
+		err = getJSON(source, &index)
+	} else {
+		var data []byte
+		if data, err = ioutil.ReadFile(localPath(source)); err == nil {
+			err = json.Unmarshal(data, &index)
+		}
+	}
+	if err != nil {
+		return index, errors.Wrap(err, "Cannot read goup release index")
+	}
+	return index, nil
+}
+
+// Latest returns the newest release having an executable for goos and arch.
+// Releases with version not in x.y.z form are ignored.
+func (idx SelfIndex) Latest(goos, arch string) (SelfRelease, SelfReleaseFile, bool) {
+	var best SelfRelease
+	var bestVer VersionInfo
+	var bestFile SelfReleaseFile
+	found := false
+	for _, r := range idx.Releases {
+		v, err := ParseSelfVersion(r.Version)
+		if err != nil || found && CompareVersion(v, bestVer) <= 0 {
+			continue
+		}
+		for _, f := range r.Files {
+			if f.OS == goos && f.Arch == arch {
+				best, bestVer, bestFile, found = r, v, f, true
+				break
+			}
+		}
+	}
+	return best, bestFile, found
+}
+
+// ParseSelfVersion parses goup version, with or without v prefix
+func ParseSelfVersion(version string) (VersionInfo, error) {
+	return ExtractVersionInfo(strings.TrimPrefix(strings.TrimSpace(version), "v"))
+}
+
+// DownloadSelfRelease downloads executable f listed in index at source to a
+// new file in dir and verifies its SHA-256. dir should be on the same file
+// system as the executable it replaces. Caller removes the file.
+func DownloadSelfRelease(source string, f SelfReleaseFile, dir string) (string, error) {
+	if len(f.SHA256) != sha256.Size*2 {
+		return "", errors.New("Release index has no valid SHA-256 for " + f.URL)
+	}
+	// Windows only runs files named .exe
+	pattern := "goup-self-"
+	if runtime.GOOS == "windows" {
+		pattern = "goup-self-*.exe"
+	}
+	tmp, err := ioutil.TempFile(dir, pattern)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	ref := resolveIndexRef(source, f.URL)
+	if isHTTPURL(ref) {
+		var resp io.ReadCloser
+		if resp, err = openHTTP(ref); err == nil {
+			_, err = io.Copy(io.MultiWriter(tmp, h), resp)
+			resp.Close()
+		}
+	} else {
+		var src *os.File
+		if src, err = os.Open(ref); err == nil {
+			_, err = io.Copy(io.MultiWriter(tmp, h), src)
+			src.Close()
+		}
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		if got := hex.EncodeToString(h.Sum(nil)); got != strings.ToLower(f.SHA256) {
+			err = fmt.Errorf("Checksum mismatch for %s: got %s, want %s", f.URL, got, f.SHA256)
+		}
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0755)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", errors.Wrap(err, "Cannot download goup")
+	}
+	return tmp.Name(), nil
+}
+
+// ReplaceExecutable renames newFile over exe. Windows cannot replace a
+// running executable, so exe is moved to exe.old first, to be removed by
+// RemoveOldExecutable after it exits.
+func ReplaceExecutable(exe, newFile string) error {
+	if runtime.GOOS != "windows" {
+		return os.Rename(newFile, exe)
+	}
+	old := exe + ".old"
+	os.Remove(old)
+	if err := os.Rename(exe, old); err != nil {
+		return err
+	}
+	if err := os.Rename(newFile, exe); err != nil {
+		os.Rename(old, exe)
+		return err
+	}
+	return nil
+}
+
+// RemoveOldExecutable removes executable left by ReplaceExecutable on
+// Windows
+func RemoveOldExecutable(exe string) {
+	os.Remove(exe + ".old")
+}
+
+func isHTTPURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+func localPath(s string) string {
+	if strings.HasPrefix(s, "file://") {
+		return filepath.FromSlash(strings.TrimPrefix(s, "file://"))
+	}
+	return s
+}
+
+// resolveIndexRef resolves ref relative to index at source
+func resolveIndexRef(source, ref string) string {
+	if isHTTPURL(ref) {
+		return ref
+	}
+	if isHTTPURL(source) {
+		base, err := url.Parse(source)
+		r, refErr := url.Parse(ref)
+		if err != nil || refErr != nil {
+			return ref
+		}
+		return base.ResolveReference(r).String()
+	}
+	ref = localPath(ref)
+	if filepath.IsAbs(ref) {
+		return ref
+	}
+	return filepath.Join(filepath.Dir(localPath(source)), filepath.FromSlash(ref))
+}
+
+func openHTTP(rawURL string) (io.ReadCloser, error) {
+	resp, err := httpClient.Get(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		resp.Body.Close()
+		return nil, errors.New("Error code: " + strconv.Itoa(resp.StatusCode))
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,121 @@
+package goup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSelfIndexLatest(t *testing.T) {
+	index := SelfIndex{Releases: []SelfRelease{
+		{"1.2.0", []SelfReleaseFile{{OS: "linux", Arch: "amd64", URL: "a"}, {OS: "darwin", Arch: "arm64", URL: "b"}}},
+		{"v1.10.1", []SelfReleaseFile{{OS: "linux", Arch: "amd64", URL: "c"}}},
+		{"1.3.0", []SelfReleaseFile{{OS: "darwin", Arch: "arm64", URL: "d"}}},
+		{"nightly", []SelfReleaseFile{{OS: "windows", Arch: "amd64", URL: "e"}}},
+	}}
+	tests := []struct {
+		name       string
+		goos, arch string
+		version    string
+		url        string
+		found      bool
+	}{
+		{"TestCase 1", "linux", "amd64", "v1.10.1", "c", true},
+		{"TestCase 2", "darwin", "arm64", "1.3.0", "d", true},
+		{"TestCase 3", "windows", "amd64", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, f, found := index.Latest(tt.goos, tt.arch)
+			if found != tt.found || r.Version != tt.version || f.URL != tt.url {
+				t.Errorf("Latest() = %q, %q, %v, want %q, %q, %v", r.Version, f.URL, found, tt.version, tt.url, tt.found)
+			}
+		})
+	}
+}
+
+func TestSelfUpdate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goup-selfupdate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	binary := []byte("new goup")
+	sum := sha256.Sum256(binary)
+	index := fmt.Sprintf(`{"releases": [{"version": "1.3.0", "files": [
+		{"os": "linux", "arch": "amd64", "url": "bin/goup", "sha256": "%s"},
+		{"os": "linux", "arch": "arm64", "url": "bin/goup", "sha256": "%s"}]}]}`,
+		hex.EncodeToString(sum[:]), strings.Repeat("0", 64))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/goup/index.json":
+			w.Write([]byte(index))
+		case "/goup/bin/goup":
+			w.Write(binary)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ioutil.WriteFile(filepath.Join(dir, "index.json"), []byte(index), 0644)
+	os.MkdirAll(filepath.Join(dir, "bin"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "bin", "goup"), binary, 0755)
+
+	for _, source := range []string{server.URL + "/goup/index.json", filepath.Join(dir, "index.json")} {
+		idx, err := FetchSelfIndex(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, f, found := idx.Latest("linux", "amd64")
+		if !found {
+			t.Fatalf("Latest() from %s found nothing", source)
+		}
+		newExe, err := DownloadSelfRelease(source, f, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data, _ := ioutil.ReadFile(newExe); string(data) != string(binary) {
+			t.Errorf("DownloadSelfRelease() from %s = %q", source, data)
+		}
+
+		exe := filepath.Join(dir, "goup")
+		ioutil.WriteFile(exe, []byte("old goup"), 0755)
+		if err := ReplaceExecutable(exe, newExe); err != nil {
+			t.Fatal(err)
+		}
+		RemoveOldExecutable(exe)
+		if data, _ := ioutil.ReadFile(exe); string(data) != string(binary) {
+			t.Errorf("Executable after ReplaceExecutable() = %q", data)
+		}
+		if _, err := os.Stat(exe + ".old"); !os.IsNotExist(err) {
+			t.Error("Old executable is left")
+		}
+
+		_, f, _ = idx.Latest("linux", "arm64")
+		if _, err := DownloadSelfRelease(source, f, dir); err == nil || !strings.Contains(err.Error(), "Checksum mismatch") {
+			t.Errorf("DownloadSelfRelease() with wrong checksum error = %v", err)
+		}
+	}
+	f := SelfReleaseFile{URL: "missing", SHA256: strings.Repeat("0", 64)}
+	if _, err := DownloadSelfRelease(server.URL+"/goup/index.json", f, dir); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("DownloadSelfRelease() of missing file error = %v", err)
+	}
+	if _, err := DownloadSelfRelease(server.URL+"/goup/index.json", SelfReleaseFile{URL: "bin/goup"}, dir); err == nil {
+		t.Error("DownloadSelfRelease() without checksum succeeded")
+	}
+	entries, _ := ioutil.ReadDir(dir)
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), "goup-self-") {
+			t.Errorf("Temporary file %s is left", e.Name())
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package goup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Shells lists shells supported by ShellEnv and ShellHook
+var Shells = []string{"bash", "zsh", "fish", "powershell"}
+
+// EnvVar is an environment variable to set, or to unset if Value is empty
+type EnvVar struct {
+	Name  string
+	Value string
+}
+
+// DetectShell guesses user's shell from $SHELL, defaulting to bash, or
+// powershell on Windows
+func DetectShell() string {
+	name := strings.TrimSuffix(filepath.Base(os.Getenv("SHELL")), ".exe")
+	for _, sh := range Shells {
+		if name == sh {
+			return sh
+		}
+	}
+	if name == "pwsh" || runtime.GOOS == "windows" {
+		return "powershell"
+	}
+	return "bash"
+}
+
+// ActivateEnv returns environment variables selecting Go installed in
+// goroot, given current environment by getenv: bin directory of goroot is put
+// first on PATH and GOROOT is set. bin directories of other versions in
+// VersionsDir are removed from PATH so switching versions does not pile them
+// up. If goroot is empty, only those directories are removed, and GOROOT is
+// unset if it points into VersionsDir.
+func ActivateEnv(goroot string, getenv func(string) string) []EnvVar {
+	entries := make([]string, 0)
+	if goroot != "" {
+		entries = append(entries, filepath.Join(goroot, "bin"))
+	}
+	versions := filepath.Clean(VersionsDir())
+	for _, entry := range filepath.SplitList(getenv("PATH")) {
+		clean := filepath.Clean(entry)
+		if filepath.Base(clean) == "bin" && filepath.Dir(filepath.Dir(clean)) == versions {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	vars := []EnvVar{{"PATH", strings.Join(entries, string(os.PathListSeparator))}}
+	if cur := getenv("GOROOT"); goroot != "" || filepath.Dir(filepath.Clean(cur)) == versions {
+		vars = append(vars, EnvVar{"GOROOT", goroot})
+	}
+	return vars
+}
+
+// ShellEnv formats vars as commands of shell, to be evaluated by it
+func ShellEnv(shell string, vars []EnvVar) (string, error) {
+	var sb strings.Builder
+	for _, v := range vars {
+		switch shell {
+		case "bash", "zsh":
+			if v.Value == "" {
+				fmt.Fprintf(&sb, "unset %s\n", v.Name)
+			} else {
+				fmt.Fprintf(&sb, "export %s=%s\n", v.Name, quotePOSIX(v.Value))
+			}
+		case "fish":
+			if v.Value == "" {
+				fmt.Fprintf(&sb, "set -e %s\n", v.Name)
+				continue
+			}
+			// PATH is a list in fish
+			values := []string{v.Value}
+			if v.Name == "PATH" {
+				values = filepath.SplitList(v.Value)
+			}
+			for i := range values {
+				values[i] = quoteFish(values[i])
+			}
+			fmt.Fprintf(&sb, "set -gx %s %s\n", v.Name, strings.Join(values, " "))
+		case "powershell":
+			if v.Value == "" {
+				fmt.Fprintf(&sb, "Remove-Item Env:%s -ErrorAction SilentlyContinue\n", v.Name)
+			} else {
+				fmt.Fprintf(&sb, "$env:%s = %s\n", v.Name, quotePowerShell(v.Value))
+			}
+		default:
+			return "", fmt.Errorf("Unsupported shell %s, use one of %s", shell, strings.Join(Shells, ", "))
+		}
+	}
+	return sb.String(), nil
+}
+
+// ShellHook returns the script run by `eval "$(goup init -)"`, which
+// evaluates `goup env --hook` of goup executable exe whenever the shell
+// changes directory
+func ShellHook(shell, exe string) (string, error) {
+	switch shell {
+	case "bash":
+		return fmt.Sprintf(`_goup_hook() {
+  local status=$?
+  if [ "${_GOUP_DIR-}" != "$PWD" ]; then
+    _GOUP_DIR=$PWD
+    eval "$(%[1]s env --shell bash --hook)"
+  fi
+  return $status
+}
+case ";${PROMPT_COMMAND-};" in
+  *";_goup_hook;"*) ;;
+  *) PROMPT_COMMAND="_goup_hook${PROMPT_COMMAND:+;$PROMPT_COMMAND}" ;;
+esac
+`, quotePOSIX(exe)), nil
+	case "zsh":
+		return fmt.Sprintf(`_goup_hook() {
+  eval "$(%[1]s env --shell zsh --hook)"
+}
+autoload -Uz add-zsh-hook
+add-zsh-hook chpwd _goup_hook
+_goup_hook
+`, quotePOSIX(exe)), nil
+	case "fish":
+		return fmt.Sprintf(`function _goup_hook --on-variable PWD
+  %[1]s env --shell fish --hook | source
+end
+_goup_hook
+`, quoteFish(exe)), nil
+	case "powershell":
+		return fmt.Sprintf(`$global:_goupPrompt = $function:prompt
+$global:_goupDir = $null
+function global:prompt {
+  if ($global:_goupDir -ne $PWD.Path) {
+    $global:_goupDir = $PWD.Path
+    & %[1]s env --shell powershell --hook | Out-String | Invoke-Expression
+  }
+  & $global:_goupPrompt
+}
+`, quotePowerShell(exe)), nil
+	}
+	return "", fmt.Errorf("Unsupported shell %s, use one of %s", shell, strings.Join(Shells, ", "))
+}
+
+func quotePOSIX(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+func quoteFish(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+func quotePowerShell(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
@@ -0,0 +1,107 @@
+package goup
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestActivateEnv(t *testing.T) {
+	setGoupHome(t, filepath.FromSlash("/goup"))
+	sep := string(os.PathListSeparator)
+	old := filepath.Join(VersionsDir(), "go1.21.5")
+	root := filepath.Join(VersionsDir(), "go1.22.1")
+	usr := filepath.FromSlash("/usr/bin")
+	path := strings.Join([]string{filepath.Join(old, "bin"), usr}, sep)
+
+	tests := []struct {
+		name   string
+		goroot string
+		env    map[string]string
+		want   []EnvVar
+	}{
+		{"TestCase 1", root, map[string]string{"PATH": path, "GOROOT": old},
+			[]EnvVar{{"PATH", filepath.Join(root, "bin") + sep + usr}, {"GOROOT", root}}},
+		{"TestCase 2", "", map[string]string{"PATH": path, "GOROOT": old},
+			[]EnvVar{{"PATH", usr}, {"GOROOT", ""}}},
+		{"TestCase 3", "", map[string]string{"PATH": usr, "GOROOT": filepath.FromSlash("/opt/go")},
+			[]EnvVar{{"PATH", usr}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ActivateEnv(tt.goroot, func(key string) string { return tt.env[key] })
+			if len(got) != len(tt.want) {
+				t.Fatalf("ActivateEnv() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("ActivateEnv() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestShellEnv(t *testing.T) {
+	vars := []EnvVar{{"GOROOT", "/it's/go"}, {"GOPROXY", ""}}
+	tests := []struct {
+		name  string
+		shell string
+		want  string
+	}{
+		{"TestCase 1", "bash", "export GOROOT='/it'\\''s/go'\nunset GOPROXY\n"},
+		{"TestCase 2", "fish", "set -gx GOROOT '/it\\'s/go'\nset -e GOPROXY\n"},
+		{"TestCase 3", "powershell", "$env:GOROOT = '/it''s/go'\nRemove-Item Env:GOPROXY -ErrorAction SilentlyContinue\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ShellEnv(tt.shell, vars)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("ShellEnv() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if _, err := ShellEnv("tcsh", vars); err == nil {
+		t.Error("ShellEnv() accepted unsupported shell")
+	}
+	if _, err := ShellHook("tcsh", "goup"); err == nil {
+		t.Error("ShellHook() accepted unsupported shell")
+	}
+
+	// Evaluated by a POSIX shell, the value is unchanged
+	sh, err := exec.LookPath("sh")
+	if err != nil || runtime.GOOS == "windows" {
+		return
+	}
+	script, _ := ShellEnv("bash", vars)
+	out, err := exec.Command(sh, "-c", script+`printf %s "$GOROOT"`).Output()
+	if err != nil || string(out) != "/it's/go" {
+		t.Errorf("Evaluated GOROOT = %q, %v", out, err)
+	}
+}
+
+func TestShellHook(t *testing.T) {
+	for _, shell := range Shells {
+		hook, err := ShellHook(shell, "/opt/my goup/goup")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(hook, "/opt/my goup/goup' env --shell "+shell+" --hook") {
+			t.Errorf("ShellHook(%s) does not run goup env:\n%s", shell, hook)
+		}
+	}
+	bash, err := exec.LookPath("bash")
+	if err != nil || runtime.GOOS == "windows" {
+		return
+	}
+	hook, _ := ShellHook("bash", "goup")
+	if out, err := exec.Command(bash, "-n", "-c", hook).CombinedOutput(); err != nil {
+		t.Errorf("bash hook has syntax error: %s", out)
+	}
+}
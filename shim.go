@@ -0,0 +1,273 @@
+package goup
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// VersionEnv selects Go version regardless of directory
+	VersionEnv = "GOUP_VERSION"
+	// VersionFileName is the per-directory version file
+	VersionFileName = ".go-version"
+)
+
+// ShimTools are the executables in GOROOT/bin dispatched by shims
+var ShimTools = []string{"go", "gofmt"}
+
+// ShimsDir is where goup places shims, to be put on PATH
+func ShimsDir() string {
+	return filepath.Join(HomeDir(), "shims")
+}
+
+// DefaultVersionFile holds the global default version, used when no other
+// version is requested
+func DefaultVersionFile() string {
+	return filepath.Join(HomeDir(), "version")
+}
+
+// ErrNoVersionRequested is returned by RequestedVersion when neither
+// environment, directories nor global default request a version
+var ErrNoVersionRequested = errors.New("No Go version is requested, set one with goup default <version>")
+
+// VersionRequest is a version constraint and where it comes from
+type VersionRequest struct {
+	Constraint Constraint
+	// Source is VersionEnv or the file requesting the version
+	Source string
+	// Reason explains how Source requests the version
+	Reason string
+}
+
+// Resolution is the installed version selected for a VersionRequest
+type Resolution struct {
+	Request VersionRequest
+	Version VersionInfo
+	GoRoot  string
+}
+
+// NotInstalledError is returned by Resolve when no installed version
+// satisfies the request
+type NotInstalledError struct {
+	Request VersionRequest
+}
+
+func (e *NotInstalledError) Error() string {
+	return fmt.Sprintf("Go %v requested by %s is not installed, install it with goup install", e.Request.Constraint, e.Request.Source)
+}
+
+// RequestedVersion finds the Go version requested for dir. In order of
+// precedence it is given by
+//
+//  1. $GOUP_VERSION
+//  2. .go-version, or toolchain line of go.mod, in dir or the nearest parent
+//     having either (.go-version wins if a directory has both)
+//  3. the global default in DefaultVersionFile
+func RequestedVersion(dir string) (VersionRequest, error) {
+	if v := os.Getenv(VersionEnv); v != "" {
+		return newVersionRequest(v, VersionEnv, "environment variable")
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return VersionRequest{}, err
+	}
+	for {
+		file := filepath.Join(dir, VersionFileName)
+		if v, err := readVersionFile(file); err == nil {
+			return newVersionRequest(v, file, "version file")
+		} else if !os.IsNotExist(err) {
+			return VersionRequest{}, err
+		}
+		file = filepath.Join(dir, "go.mod")
+		if v, err := goModToolchain(file); err == nil && v != "" {
+			return newVersionRequest(v, file, "toolchain line")
+		} else if err != nil && !os.IsNotExist(err) {
+			return VersionRequest{}, err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	file := DefaultVersionFile()
+	if v, err := readVersionFile(file); err == nil {
+		return newVersionRequest(v, file, "global default")
+	} else if !os.IsNotExist(err) {
+		return VersionRequest{}, err
+	}
+	return VersionRequest{}, ErrNoVersionRequested
+}
+
+func newVersionRequest(spec, source, reason string) (VersionRequest, error) {
+	c, err := ParseConstraint(spec)
+	if err != nil {
+		return VersionRequest{}, errors.Wrapf(err, "Invalid version in %s", source)
+	}
+	return VersionRequest{Constraint: c, Source: source, Reason: reason}, nil
+}
+
+// readVersionFile returns the first non-empty, non-comment line of file
+func readVersionFile(file string) (string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return line, nil
+		}
+	}
+	return "", fmt.Errorf("%s is empty", file)
+}
+
+// goModToolchain returns the constraint of toolchain line of go.mod, or "" if
+// there is none. The line names the minimum toolchain, and a suffix of custom
+// builds (go1.21.0-custom, go1.22.1+auto) is dropped.
+func goModToolchain(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "toolchain" && fields[1] != "default" {
+			name := strings.TrimPrefix(fields[1], "go")
+			if i := strings.IndexAny(name, "+-"); i >= 0 {
+				name = name[:i]
+			}
+			return ">=" + name, nil
+		}
+	}
+	return "", scanner.Err()
+}
+
+// InstalledVersions lists Go versions for host platform installed in
+// VersionsDir, newest first
+func InstalledVersions() ([]VersionInfo, error) {
+	installs, err := ManagedInstalls()
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]VersionInfo, 0, len(installs))
+	for _, inst := range installs {
+		if inst.GOOS == runtime.GOOS && inst.Arch == runtime.GOARCH {
+			versions = append(versions, inst.Version)
+		}
+	}
+	return versions, nil
+}
+
+// Resolve finds the newest installed version satisfying the version
+// requested for dir
+func Resolve(dir string) (Resolution, error) {
+	req, err := RequestedVersion(dir)
+	if err != nil {
+		return Resolution{}, err
+	}
+	v, ok, err := NewestInstalled(req.Constraint)
+	if err != nil {
+		return Resolution{}, err
+	}
+	if !ok {
+		return Resolution{Request: req}, &NotInstalledError{req}
+	}
+	return Resolution{Request: req, Version: v, GoRoot: ManagedRoot(v, runtime.GOOS, runtime.GOARCH)}, nil
+}
+
+// NewestInstalled returns the newest installed version satisfying c, with
+// false if there is none
+func NewestInstalled(c Constraint) (VersionInfo, bool, error) {
+	installed, err := InstalledVersions()
+	if err != nil {
+		return VersionInfo{}, false, err
+	}
+	for _, v := range installed {
+		if c.Match(v) {
+			return v, true, nil
+		}
+	}
+	return VersionInfo{}, false, nil
+}
+
+// DefaultVersion returns the global default version, or "" if it is not set
+func DefaultVersion() (string, error) {
+	v, err := readVersionFile(DefaultVersionFile())
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return v, err
+}
+
+// SetDefaultVersion writes the global default version
+func SetDefaultVersion(spec string) error {
+	if _, err := ParseConstraint(spec); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(HomeDir(), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(DefaultVersionFile(), []byte(spec+"\n"), 0644)
+}
+
+// ClearDefaultVersion removes the global default version
+func ClearDefaultVersion() error {
+	err := os.Remove(DefaultVersionFile())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// InstallShims creates go and gofmt shims in dir pointing to goup executable
+// exe. Shims are symbolic links, or copies on Windows.
+func InstallShims(exe, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, tool := range ShimTools {
+		shim := filepath.Join(dir, tool+exeSuffix(runtime.GOOS))
+		if err := os.Remove(shim); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "Cannot replace existing shim")
+		}
+		var err error
+		if runtime.GOOS == "windows" {
+			var fi os.FileInfo
+			if fi, err = os.Stat(exe); err == nil {
+				err = copyFile(exe, shim, fi, CopyAuto)
+			}
+		} else {
+			err = os.Symlink(exe, shim)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "Cannot create %s shim", tool)
+		}
+	}
+	return nil
+}
+
+// ShimTool returns the tool a shim dispatches to if goup is invoked through
+// one (as go or gofmt), or "" otherwise
+func ShimTool(arg0 string) string {
+	name := strings.TrimSuffix(filepath.Base(arg0), ".exe")
+	for _, tool := range ShimTools {
+		if name == tool {
+			return tool
+		}
+	}
+	return ""
+}
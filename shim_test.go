@@ -0,0 +1,226 @@
+package goup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// setGoupHome points GOUP_HOME to dir until the test ends
+func setGoupHome(t *testing.T, dir string) {
+	t.Helper()
+	t.Setenv("GOUP_HOME", dir)
+}
+
+func TestRequestedVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goup-shim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	home := filepath.Join(dir, "home")
+	setGoupHome(t, home)
+	defer os.Setenv(VersionEnv, os.Getenv(VersionEnv))
+	os.Unsetenv(VersionEnv)
+
+	// proj/.go-version, proj/go.mod, proj/mod/go.mod, proj/both/{.go-version,go.mod}
+	proj := filepath.Join(dir, "proj")
+	files := map[string]string{
+		filepath.Join(proj, VersionFileName):         "# pinned\n1.21\n",
+		filepath.Join(proj, "mod", "go.mod"):         "module example.com/m\n\ngo 1.22\ntoolchain go1.22.3 // pinned\n",
+		filepath.Join(proj, "nomod", "go.mod"):       "module example.com/n\n\ngo 1.22\n",
+		filepath.Join(proj, "both", VersionFileName): "1.20.1",
+		filepath.Join(proj, "both", "go.mod"):        "module example.com/b\ntoolchain go1.22.3\n",
+	}
+	for file, content := range files {
+		os.MkdirAll(filepath.Dir(file), 0755)
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.MkdirAll(filepath.Join(proj, "mod", "sub"), 0755)
+	os.MkdirAll(filepath.Join(dir, "other"), 0755)
+
+	tests := []struct {
+		name       string
+		dir        string
+		env        string
+		want       string
+		wantSource string
+	}{
+		{"TestCase 1", filepath.Join(proj, "mod", "sub"), "", ">=1.22.3", filepath.Join(proj, "mod", "go.mod")},
+		{"TestCase 2", filepath.Join(proj, "nomod"), "", "1.21", filepath.Join(proj, VersionFileName)},
+		{"TestCase 3", filepath.Join(proj, "both"), "", "1.20.1", filepath.Join(proj, "both", VersionFileName)},
+		{"TestCase 4", filepath.Join(proj, "mod"), "1.19", "1.19", VersionEnv},
+		{"TestCase 5", filepath.Join(dir, "other"), "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.env != "" {
+				os.Setenv(VersionEnv, tt.env)
+				defer os.Unsetenv(VersionEnv)
+			}
+			req, err := RequestedVersion(tt.dir)
+			if tt.want == "" {
+				if err != ErrNoVersionRequested {
+					t.Errorf("RequestedVersion() error = %v, want ErrNoVersionRequested", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RequestedVersion() error = %v", err)
+			}
+			if got := req.Constraint.String(); got != tt.want {
+				t.Errorf("RequestedVersion() = %v, want %v", got, tt.want)
+			}
+			if req.Source != tt.wantSource {
+				t.Errorf("RequestedVersion() source = %v, want %v", req.Source, tt.wantSource)
+			}
+		})
+	}
+
+	// Global default applies outside any project
+	if err := SetDefaultVersion("1.22"); err != nil {
+		t.Fatal(err)
+	}
+	req, err := RequestedVersion(filepath.Join(dir, "other"))
+	if err != nil || req.Source != DefaultVersionFile() {
+		t.Errorf("RequestedVersion() = %+v, %v; want global default", req, err)
+	}
+	if err := SetDefaultVersion("1.x"); err == nil {
+		t.Error("SetDefaultVersion() accepted invalid version")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goup-shim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	setGoupHome(t, dir)
+	defer os.Setenv(VersionEnv, os.Getenv(VersionEnv))
+
+	other := "linux"
+	if runtime.GOOS == "linux" {
+		other = "windows"
+	}
+	for _, name := range []string{"go1.21.4", "go1.21.10", "go1.22.1", "go1.23.0." + other + "-amd64", "notgo"} {
+		os.MkdirAll(filepath.Join(VersionsDir(), name), 0755)
+	}
+	installed, err := InstalledVersions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(installed) != 3 || installed[0].String() != "1.22.1" || installed[2].String() != "1.21.4" {
+		t.Errorf("InstalledVersions() = %v", installed)
+	}
+
+	tests := []struct {
+		name string
+		env  string
+		want string
+	}{
+		{"TestCase 1", "1.21", "1.21.10"},
+		{"TestCase 2", "1.21.4", "1.21.4"},
+		{"TestCase 3", ">=1.21", "1.22.1"},
+		{"TestCase 4", "1.23", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv(VersionEnv, tt.env)
+			res, err := Resolve(dir)
+			if tt.want == "" {
+				if _, ok := err.(*NotInstalledError); !ok {
+					t.Errorf("Resolve() error = %v, want *NotInstalledError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			if res.Version.String() != tt.want {
+				t.Errorf("Resolve() = %v, want %v", res.Version, tt.want)
+			}
+			if want := filepath.Join(VersionsDir(), "go"+tt.want); res.GoRoot != want {
+				t.Errorf("Resolve() GoRoot = %v, want %v", res.GoRoot, want)
+			}
+		})
+	}
+}
+
+func TestGoModToolchain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goup-shim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"TestCase 1", "module m\n\ngo 1.21\n\ntoolchain go1.21.5\n", ">=1.21.5"},
+		{"TestCase 2", "module m\n\ngo 1.21\n", ""},
+		{"TestCase 3", "module m\n// toolchain go1.20\n", ""},
+		{"TestCase 4", "module m\ntoolchain default\n", ""},
+		{"TestCase 5", "module m\ntoolchain go1.22.1+auto\n", ">=1.22.1"},
+		{"TestCase 6", "module m\ntoolchain go1.21.0-custom\n", ">=1.21.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(dir, "go.mod")
+			ioutil.WriteFile(file, []byte(tt.content), 0644)
+			got, err := goModToolchain(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("goModToolchain() = %q, want %q", got, tt.want)
+			}
+			if got == "" {
+				return
+			}
+			// Newer toolchains satisfy the line, older do not
+			c, err := ParseConstraint(got)
+			if err != nil {
+				t.Fatalf("ParseConstraint(%q) error = %v", got, err)
+			}
+			if !c.Match(mustVersion(t, "1.23.0")) || c.Match(mustVersion(t, "1.20.9")) {
+				t.Errorf("Constraint %q does not work as a minimum", got)
+			}
+		})
+	}
+}
+
+func TestInstallShims(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goup-shim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	exe := filepath.Join(dir, "goup")
+	ioutil.WriteFile(exe, []byte("goup"), 0755)
+	shims := filepath.Join(dir, "shims")
+	// Installing twice replaces existing shims
+	for i := 0; i < 2; i++ {
+		if err := InstallShims(exe, shims); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tool := range ShimTools {
+		shim := filepath.Join(shims, tool+exeSuffix(runtime.GOOS))
+		data, err := ioutil.ReadFile(shim)
+		if err != nil || string(data) != "goup" {
+			t.Errorf("shim %s = %q, %v", shim, data, err)
+		}
+		if ShimTool(shim) != tool {
+			t.Errorf("ShimTool(%q) = %q, want %q", shim, ShimTool(shim), tool)
+		}
+	}
+	if got := ShimTool(exe); got != "" {
+		t.Errorf("ShimTool(%q) = %q, want \"\"", exe, got)
+	}
+}
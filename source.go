@@ -0,0 +1,155 @@
+package goup
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// SourceUrl returns the URL of Go source archive of version, e.g.
+// https://dl.google.com/go/go1.21.1.src.tar.gz
+func SourceUrl(version VersionInfo) string {
+	replacer := strings.NewReplacer("[version]", releaseName(version),
+		".[os]-[arch]", ".src",
+		"[ext]", "tar.gz")
+	return replacer.Replace(DownloadURLWithPattern)
+}
+
+// DownloadSource downloads Go source archive of version into dir and verifies
+// it against the published SHA-256, like DownloadRelease
+func DownloadSource(version VersionInfo, dir string, progress func(totalSize int64, src io.Reader) io.Reader) (string, error) {
+	return downloadVerified(SourceUrl(version), dir, progress)
+}
+
+// MinimumBootstrap returns the oldest Go release which can build version
+// from source. Go 1.4 and older are built with a C compiler and are not
+// supported.
+func MinimumBootstrap(version VersionInfo) (VersionInfo, error) {
+	switch {
+	case version.Major != 1 || version.Minor < 5:
+		return VersionInfo{}, fmt.Errorf("Building Go %v from source is not supported", version)
+	case version.Minor < 20:
+		return VersionInfo{Major: 1, Minor: 4}, nil
+	case version.Minor < 22:
+		return VersionInfo{Major: 1, Minor: 17, Build: 13}, nil
+	}
+	// Since Go 1.22, Go 1.N needs a patch release of Go 1.N-2, rounded down
+	// to an even minor version
+	return VersionInfo{Major: 1, Minor: version.Minor&^1 - 2, Build: 6}, nil
+}
+
+// SelectBootstrap picks the newest stable toolchain among candidates for the
+// host platform which can build version from source
+func SelectBootstrap(version VersionInfo, candidates []ManagedInstall) (ManagedInstall, error) {
+	min, err := MinimumBootstrap(version)
+	if err != nil {
+		return ManagedInstall{}, err
+	}
+	var best ManagedInstall
+	found := false
+	for _, c := range candidates {
+		if c.GOOS != runtime.GOOS || c.Arch != runtime.GOARCH || c.Version.RC || c.Version.Beta {
+			continue
+		}
+		if CompareVersion(c.Version, min) >= 0 && (!found || CompareVersion(c.Version, best.Version) > 0) {
+			best, found = c, true
+		}
+	}
+	if !found {
+		return ManagedInstall{}, fmt.Errorf("Building Go %v needs Go %v or later to bootstrap, install it with goup install %v", version, min, min)
+	}
+	return best, nil
+}
+
+// CheckBootstrap tells if Go installed in root can build version from source,
+// by the version in its VERSION file
+func CheckBootstrap(version VersionInfo, root string) error {
+	min, err := MinimumBootstrap(version)
+	if err != nil {
+		return err
+	}
+	line, err := readRootVersion(root)
+	if err != nil {
+		return errors.Wrapf(err, "%s is not a Go installation", root)
+	}
+	bootVer, err := ExtractVersionInfo(strings.TrimPrefix(line, "go"))
+	if err != nil {
+		return fmt.Errorf("Cannot tell version of Go in %s from %q", root, line)
+	}
+	if CompareVersion(bootVer, min) < 0 {
+		return fmt.Errorf("Building Go %v needs Go %v or later to bootstrap, Go in %s is %v", version, min, root, bootVer)
+	}
+	return nil
+}
+
+// SourceBuildOptions controls how BuildFromSource runs make.bash
+type SourceBuildOptions struct {
+	// Bootstrap is GOROOT of the toolchain building Go
+	Bootstrap string
+	// GOEXPERIMENT and CGOEnabled ("0" or "1") are left to make.bash if empty
+	GOEXPERIMENT string
+	CGOEnabled   string
+	// Output receives output of make.bash
+	Output io.Writer
+}
+
+// BuildFromSource extracts Go source archive to a staging directory next to
+// root, builds it for the host platform with make.bash, verifies the result
+// and moves it to root, which must not exist
+func BuildFromSource(archive, root string, version VersionInfo, opts SourceBuildOptions, progCback func(format string, arg ...interface{})) error {
+	return installStaged(root, version, runtime.GOOS, runtime.GOARCH, func(staging string) error {
+		f, err := os.Open(archive)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := ExtractArchive(f, -1, staging, progCback); err != nil {
+			return err
+		}
+
+		var cmd *exec.Cmd
+		if runtime.GOOS == "windows" {
+			cmd = exec.Command("cmd", "/c", "make.bat")
+		} else {
+			cmd = exec.Command("bash", "make.bash")
+		}
+		cmd.Dir = filepath.Join(staging, "src")
+		cmd.Env = sourceBuildEnv(os.Environ(), root, opts)
+		cmd.Stdout, cmd.Stderr = opts.Output, opts.Output
+		if err := cmd.Run(); err != nil {
+			return errors.Wrap(err, "Building Go failed")
+		}
+		// Build cache is not part of a release
+		return os.RemoveAll(filepath.Join(staging, "pkg", "obj"))
+	})
+}
+
+// sourceBuildEnv returns environment of make.bash building Go to be moved to
+// root. Settings of the calling Go toolchain are removed so they do not leak
+// into the build.
+func sourceBuildEnv(env []string, root string, opts SourceBuildOptions) []string {
+	drop := map[string]bool{"GOROOT": true, "GOBIN": true, "GOOS": true, "GOARCH": true, "GOFLAGS": true,
+		"GOTOOLCHAIN": true, "GOEXPERIMENT": true, "CGO_ENABLED": true, "GOROOT_BOOTSTRAP": true, "GOROOT_FINAL": true}
+	result := make([]string, 0, len(env)+5)
+	for _, kv := range env {
+		if !drop[strings.SplitN(kv, "=", 2)[0]] {
+			result = append(result, kv)
+		}
+	}
+	// GOROOT_FINAL is ignored since Go 1.23, which finds GOROOT from the
+	// executable location
+	result = append(result, "GOROOT_BOOTSTRAP="+opts.Bootstrap, "GOROOT_FINAL="+root, "GOTOOLCHAIN=local")
+	if opts.GOEXPERIMENT != "" {
+		result = append(result, "GOEXPERIMENT="+opts.GOEXPERIMENT)
+	}
+	if opts.CGOEnabled != "" {
+		result = append(result, "CGO_ENABLED="+opts.CGOEnabled)
+	}
+	return result
+}
@@ -0,0 +1,196 @@
+package goup
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSourceUrl(t *testing.T) {
+	if got, want := SourceUrl(mustVersion(t, "1.21.1")), "https://dl.google.com/go/go1.21.1.src.tar.gz"; got != want {
+		t.Errorf("SourceUrl() = %s, want %s", got, want)
+	}
+}
+
+func TestMinimumBootstrap(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		want    string
+		wantErr bool
+	}{
+		{"TestCase 1", "1.4", "", true},
+		{"TestCase 2", "1.5", "1.4", false},
+		{"TestCase 3", "1.19.13", "1.4", false},
+		{"TestCase 4", "1.20", "1.17.13", false},
+		{"TestCase 5", "1.21.5", "1.17.13", false},
+		{"TestCase 6", "1.22.0", "1.20.6", false},
+		{"TestCase 7", "1.23rc1", "1.20.6", false},
+		{"TestCase 8", "1.24.3", "1.22.6", false},
+		{"TestCase 9", "1.27.1", "1.24.6", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MinimumBootstrap(mustVersion(t, tt.version))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("MinimumBootstrap(%s) error = %v, wantErr %v", tt.version, err, tt.wantErr)
+				return
+			}
+			if err == nil && got != mustVersion(t, tt.want) {
+				t.Errorf("MinimumBootstrap(%s) = %v, want %s", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectBootstrap(t *testing.T) {
+	host := func(v string) ManagedInstall {
+		return ManagedInstall{mustVersion(t, v), runtime.GOOS, runtime.GOARCH, "/go" + v}
+	}
+	other := ManagedInstall{mustVersion(t, "1.23.0"), "plan9", "386", "/plan9"}
+	tests := []struct {
+		name       string
+		version    string
+		candidates []ManagedInstall
+		want       string
+	}{
+		{"TestCase 1", "1.22.3", []ManagedInstall{host("1.19.13"), host("1.20.6"), host("1.21.5")}, "/go1.21.5"},
+		{"TestCase 2", "1.22.3", []ManagedInstall{host("1.20.5"), host("1.22rc1"), other}, ""},
+		{"TestCase 3", "1.19.3", []ManagedInstall{host("1.4"), host("1.17.13")}, "/go1.17.13"},
+		{"TestCase 4", "1.24.0", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SelectBootstrap(mustVersion(t, tt.version), tt.candidates)
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("SelectBootstrap() = %v, want error", got.Root)
+				}
+				return
+			}
+			if err != nil || got.Root != tt.want {
+				t.Errorf("SelectBootstrap() = %v, %v, want %s", got.Root, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckBootstrap(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goup-source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name    string
+		version string
+		content string
+		wantErr bool
+	}{
+		{"TestCase 1", "1.22.3", "go1.20.6\ntime 2023-07-11T23:18:32Z\n", false},
+		{"TestCase 2", "1.22.3", "go1.21.5", false},
+		{"TestCase 3", "1.22.3", "go1.17.13\n", true},
+		{"TestCase 4", "1.19.3", "go1.17.13\n", false},
+		{"TestCase 5", "1.22.3", "devel go1.23-4f7bd4a Tue Mar 5 10:31:02 2024 +0000", true},
+		{"TestCase 6", "1.22.3", "", true},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := filepath.Join(dir, strconv.Itoa(i))
+			os.MkdirAll(root, 0755)
+			if tt.content != "" {
+				ioutil.WriteFile(filepath.Join(root, "VERSION"), []byte(tt.content), 0644)
+			}
+			if err := CheckBootstrap(mustVersion(t, tt.version), root); (err != nil) != tt.wantErr {
+				t.Errorf("CheckBootstrap(%s) error = %v, wantErr %v", tt.version, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSourceBuildEnv(t *testing.T) {
+	env := sourceBuildEnv([]string{"PATH=/bin", "GOROOT=/usr/local/go", "GOFLAGS=-mod=vendor", "GOOS=plan9", "CGO_ENABLED=1"},
+		"/versions/go1.22.3", SourceBuildOptions{Bootstrap: "/usr/local/go", GOEXPERIMENT: "loopvar", CGOEnabled: "0"})
+	want := []string{"PATH=/bin", "GOROOT_BOOTSTRAP=/usr/local/go", "GOROOT_FINAL=/versions/go1.22.3", "GOTOOLCHAIN=local", "GOEXPERIMENT=loopvar", "CGO_ENABLED=0"}
+	if strings.Join(env, " ") != strings.Join(want, " ") {
+		t.Errorf("sourceBuildEnv() = %v, want %v", env, want)
+	}
+	env = sourceBuildEnv([]string{"GOEXPERIMENT=arenas"}, "/go", SourceBuildOptions{Bootstrap: "/boot"})
+	for _, kv := range env {
+		if strings.HasPrefix(kv, "GOEXPERIMENT=") || strings.HasPrefix(kv, "CGO_ENABLED=") {
+			t.Errorf("sourceBuildEnv() without options has %s", kv)
+		}
+	}
+}
+
+func TestBuildFromSource(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Fake make.bash cannot run on Windows")
+	}
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash is not found")
+	}
+	dir, err := ioutil.TempDir("", "goup-source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// make.bash lays out the files VerifyInstall checks and records its
+	// environment
+	tool := "pkg/tool/" + runtime.GOOS + "_" + runtime.GOARCH
+	makeBash := "set -e\ncd ..\nmkdir -p bin " + tool + " pkg/obj/go-build\n" +
+		"for f in bin/go bin/gofmt " + tool + "/compile " + tool + "/link; do cp \"$GOROOT_BOOTSTRAP/bin/go\" $f; done\n" +
+		"echo \"$GOROOT_BOOTSTRAP $GOEXPERIMENT $CGO_ENABLED $GOTOOLCHAIN\" > env.txt\n" +
+		"[ \"$FAIL\" = \"\" ]\n"
+	archive := filepath.Join(dir, "go1.22.3.src.tar.gz")
+	ioutil.WriteFile(archive, makeTarGz(t, []testEntry{
+		{"go/VERSION", tar.TypeReg, "go1.22.3\ntime 2024-05-01T19:53:23Z\n", ""},
+		{"go/src/make.bash", tar.TypeReg, makeBash, ""},
+		{"go/src/runtime/", tar.TypeDir, "", ""},
+	}), 0644)
+	bootstrap := filepath.Join(dir, "bootstrap")
+	os.MkdirAll(filepath.Join(bootstrap, "bin"), 0755)
+	goExe := "\x7fELF go"
+	if runtime.GOOS == "darwin" {
+		goExe = "\xcf\xfa\xed\xfe go"
+	}
+	ioutil.WriteFile(filepath.Join(bootstrap, "bin", "go"), []byte(goExe), 0755)
+
+	var output bytes.Buffer
+	ver := mustVersion(t, "1.22.3")
+	opts := SourceBuildOptions{Bootstrap: bootstrap, GOEXPERIMENT: "rangefunc", CGOEnabled: "0", Output: &output}
+	root := filepath.Join(dir, "versions", "go1.22.3")
+
+	os.Setenv("FAIL", "1")
+	err = BuildFromSource(archive, root, ver, opts, func(string, ...interface{}) {})
+	os.Unsetenv("FAIL")
+	if err == nil {
+		t.Error("BuildFromSource() with failing make.bash succeeded")
+	}
+	if _, err := os.Stat(root); !os.IsNotExist(err) {
+		t.Errorf("Failed build left %s", root)
+	}
+
+	if err := BuildFromSource(archive, root, ver, opts, func(string, ...interface{}) {}); err != nil {
+		t.Fatalf("BuildFromSource() error = %v, output %s", err, output.String())
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(root, "env.txt")); string(data) != bootstrap+" rangefunc 0 local\n" {
+		t.Errorf("make.bash environment = %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(root, "pkg", "obj")); !os.IsNotExist(err) {
+		t.Error("Build cache is not removed")
+	}
+	leftovers, _ := filepath.Glob(filepath.Join(dir, "versions", ".*staging*"))
+	if len(leftovers) > 0 {
+		t.Errorf("Staging directories left: %v", leftovers)
+	}
+}
@@ -0,0 +1,171 @@
+package goup
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// UpgradeRecord is the outcome of the last upgrade, kept in
+// UpgradeRecordFile
+type UpgradeRecord struct {
+	Time    time.Time
+	GoRoot  string
+	From    VersionInfo
+	To      VersionInfo
+	Success bool
+}
+
+// UpgradeRecordFile is where the last UpgradeRecord is kept
+func UpgradeRecordFile() string {
+	return filepath.Join(HomeDir(), "last-upgrade.json")
+}
+
+// LoadUpgradeRecord reads the last UpgradeRecord
+func LoadUpgradeRecord() (UpgradeRecord, error) {
+	var r UpgradeRecord
+	data, err := ioutil.ReadFile(UpgradeRecordFile())
+	if err != nil {
+		return r, err
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return r, errors.Wrap(err, "Cannot parse last upgrade record")
+	}
+	return r, nil
+}
+
+// SaveUpgradeRecord replaces the last UpgradeRecord with r
+func SaveUpgradeRecord(r UpgradeRecord) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(UpgradeRecordFile(), data); err != nil {
+		return errors.Wrap(err, "Cannot write last upgrade record")
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it, so readers never see it half written
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		// TempFile creates the file readable by owner only
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// Status is what goup status reports about a Go installation
+type Status struct {
+	GoRoot string
+	Local  VersionInfo
+	GOOS   string
+	Arch   string
+	// Latest is the newest stable release, zero if version list is not
+	// available
+	Latest VersionInfo
+	// Target is the version Policy selects, zero if unknown
+	Target VersionInfo
+	Policy string
+	// LastCheck and LastUpgrade are nil if there is none
+	LastCheck   *CheckResult   `json:",omitempty"`
+	LastUpgrade *UpgradeRecord `json:",omitempty"`
+}
+
+// UpdateAvailable tells if Target is newer than Local. known is false if
+// Target is unknown.
+func (s Status) UpdateAvailable() (available, known bool) {
+	var zero VersionInfo
+	if s.Target == zero {
+		return false, false
+	}
+	return s.Target != s.Local, true
+}
+
+// WriteMetrics writes s in Prometheus text exposition format
+func WriteMetrics(w io.Writer, s Status) error {
+	bw := bufio.NewWriter(w)
+	metric := func(name, help string, labels []string, value float64) {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s gauge\n%s", name, help, name, name)
+		if len(labels) > 0 {
+			pairs := make([]string, 0, len(labels)/2)
+			for i := 0; i+1 < len(labels); i += 2 {
+				pairs = append(pairs, labels[i]+`="`+metricLabelEscaper.Replace(labels[i+1])+`"`)
+			}
+			fmt.Fprintf(bw, "{%s}", strings.Join(pairs, ","))
+		}
+		fmt.Fprintf(bw, " %s\n", strconv.FormatFloat(value, 'f', -1, 64))
+	}
+	boolValue := func(b bool) float64 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+
+	var zero VersionInfo
+	metric("goup_local_version_info", "Go version of the installation, always 1.", []string{
+		"version", s.Local.String(), "os", s.GOOS, "arch", s.Arch, "goroot", s.GoRoot}, 1)
+	if s.Latest != zero {
+		metric("goup_latest_version_info", "Newest stable Go release, always 1.", []string{"version", s.Latest.String()}, 1)
+	}
+	if s.Target != zero {
+		metric("goup_target_version_info", "Go version the upgrade policy selects, always 1.", []string{
+			"version", s.Target.String(), "policy", s.Policy}, 1)
+	}
+	if available, known := s.UpdateAvailable(); known {
+		metric("goup_update_available", "Whether the upgrade policy selects a newer Go version.", nil, boolValue(available))
+	}
+	if c := s.LastCheck; c != nil {
+		metric("goup_last_check_timestamp", "Unix time of the last goup check.", nil, float64(c.CheckedAt.Unix()))
+		metric("goup_last_check_success", "Whether the last goup check succeeded.", nil, boolValue(c.Error == ""))
+	}
+	if u := s.LastUpgrade; u != nil {
+		metric("goup_last_upgrade_result", "Whether the last upgrade succeeded.", []string{
+			"from", u.From.String(), "to", u.To.String(), "goroot", u.GoRoot}, boolValue(u.Success))
+		metric("goup_last_upgrade_timestamp", "Unix time of the last upgrade.", nil, float64(u.Time.Unix()))
+	}
+	return bw.Flush()
+}
+
+var metricLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WriteMetricsFile writes metrics of s to path atomically, as the textfile
+// collector of node_exporter expects
+func WriteMetricsFile(path string, s Status) error {
+	var b strings.Builder
+	if err := WriteMetrics(&b, s); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, []byte(b.String())); err != nil {
+		return errors.Wrap(err, "Cannot write metrics file")
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package goup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUpgradeRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goup-status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	setGoupHome(t, dir)
+
+	if _, err := LoadUpgradeRecord(); !os.IsNotExist(err) {
+		t.Errorf("LoadUpgradeRecord() without record error = %v", err)
+	}
+	r := UpgradeRecord{time.Date(2024, 2, 6, 9, 17, 0, 0, time.UTC), "/usr/local/go", mustVersion(t, "1.21.5"), mustVersion(t, "1.21.7"), true}
+	if err := SaveUpgradeRecord(r); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadUpgradeRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Time.Equal(r.Time) || got.From != r.From || got.To != r.To || got.GoRoot != r.GoRoot || !got.Success {
+		t.Errorf("LoadUpgradeRecord() = %+v, want %+v", got, r)
+	}
+}
+
+func TestWriteMetrics(t *testing.T) {
+	tests := []struct {
+		name   string
+		status Status
+		want   []string
+		absent []string
+	}{
+		{
+			"TestCase 1",
+			Status{GoRoot: "/usr/local/go", Local: mustVersion(t, "1.21.5"), GOOS: "linux", Arch: "amd64"},
+			[]string{`goup_local_version_info{version="1.21.5",os="linux",arch="amd64",goroot="/usr/local/go"} 1`},
+			[]string{"goup_latest_version_info", "goup_update_available", "goup_last_check_timestamp", "goup_last_upgrade_result"},
+		}, {
+			"TestCase 2",
+			Status{
+				GoRoot: `C:\Program Files\Go`, Local: mustVersion(t, "1.21.5"), GOOS: "windows", Arch: "amd64",
+				Latest: mustVersion(t, "1.22.0"), Target: mustVersion(t, "1.21.7"), Policy: "patch",
+				LastCheck:   &CheckResult{CheckedAt: time.Unix(1707211020, 0), Error: "timeout"},
+				LastUpgrade: &UpgradeRecord{time.Unix(1707200000, 0), "/usr/local/go", mustVersion(t, "1.21.4"), mustVersion(t, "1.21.5"), false},
+			},
+			[]string{
+				`goup_local_version_info{version="1.21.5",os="windows",arch="amd64",goroot="C:\\Program Files\\Go"} 1`,
+				`goup_latest_version_info{version="1.22"} 1`,
+				`goup_target_version_info{version="1.21.7",policy="patch"} 1`,
+				"goup_update_available 1",
+				"goup_last_check_timestamp 1707211020",
+				"goup_last_check_success 0",
+				`goup_last_upgrade_result{from="1.21.4",to="1.21.5",goroot="/usr/local/go"} 0`,
+				"goup_last_upgrade_timestamp 1707200000",
+			},
+			nil,
+		}, {
+			"TestCase 3",
+			Status{Local: mustVersion(t, "1.21.7"), Target: mustVersion(t, "1.21.7")},
+			[]string{"goup_update_available 0"},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := WriteMetrics(&b, tt.status); err != nil {
+				t.Fatal(err)
+			}
+			lines := strings.Split(b.String(), "\n")
+			for _, want := range tt.want {
+				found := false
+				for _, l := range lines {
+					found = found || l == want
+				}
+				if !found {
+					t.Errorf("metrics has no line %s:\n%s", want, b.String())
+				}
+			}
+			for _, name := range tt.absent {
+				if strings.Contains(b.String(), name) {
+					t.Errorf("metrics has %s:\n%s", name, b.String())
+				}
+			}
+		})
+	}
+}
+
+func TestWriteMetricsFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goup-status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "textfile", "goup.prom")
+	if err := WriteMetricsFile(path, Status{Local: mustVersion(t, "1.21.5")}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil || !strings.Contains(string(data), "goup_local_version_info") {
+		t.Errorf("Metrics file = %q, %v", data, err)
+	}
+	entries, _ := ioutil.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("Temporary file is left in %s", filepath.Dir(path))
+	}
+}
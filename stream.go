@@ -0,0 +1,65 @@
+package goup
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+
+	"github.com/pkg/errors"
+)
+
+// StreamExtract downloads tar.gz archive from url and extracts it to staging
+// in one pass, hashing the response body on the way, so the archive never
+// touches the disk. staging must be discarded by caller if an error is
+// returned, including when the SHA-256 of the body does not match want.
+func StreamExtract(url, want, staging string, progress func(totalSize int64, src io.Reader) io.Reader, progCback func(format string, arg ...interface{})) error {
+	extractor, err := ExtractorFor(path.Base(url))
+	if err != nil {
+		return err
+	}
+	if _, ok := extractor.(TarGzExtractor); !ok {
+		return fmt.Errorf("Streaming is only supported for tar.gz archives, not %s", path.Base(url))
+	}
+	h := sha256.New()
+	_, err = DownloadPackage(url, func(totalSize int64, src io.Reader) error {
+		if progress != nil {
+			src = progress(totalSize, src)
+		}
+		tee := io.TeeReader(src, h)
+		gzr, err := gzip.NewReader(tee)
+		if err != nil {
+			return errors.Wrap(err, "Error opening GZip archive")
+		}
+		if err := extractTar(gzr, staging, true, progCback); err != nil {
+			return err
+		}
+		// Hash includes bytes after end of tar archive
+		_, err = io.Copy(ioutil.Discard, tee)
+		return err
+	})
+	if err != nil {
+		return errors.Wrap(err, "Cannot download "+url)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != want {
+		return fmt.Errorf("Checksum mismatch for %s: got %s, want %s", path.Base(url), got, want)
+	}
+	return nil
+}
+
+// StreamInstall downloads release of version for goos/arch and extracts it
+// to root in one pass with StreamExtract. root is only created if checksum
+// and VerifyInstall pass.
+func StreamInstall(version VersionInfo, goos, arch, root string, progress func(totalSize int64, src io.Reader) io.Reader, progCback func(format string, arg ...interface{})) error {
+	url := DownloadUrl(version, goos, arch)
+	sum, err := FetchChecksum(url)
+	if err != nil {
+		return errors.Wrap(err, "Cannot retrieve checksum")
+	}
+	return installStaged(root, version, goos, arch, func(staging string) error {
+		return StreamExtract(url, sum, staging, progress, progCback)
+	})
+}
@@ -0,0 +1,75 @@
+package goup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStreamExtract(t *testing.T) {
+	archive := makeTarGz(t, fakeGoTree("1.21.1", "linux", "amd64"))
+	// Trailing bytes after gzip stream must be hashed too
+	archive = append(archive, make([]byte, 512)...)
+	sum := sha256.Sum256(archive)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(archive)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		url     string
+		want    string
+		wantErr bool
+	}{
+		{"TestCase 1", srv.URL + "/go1.21.1.linux-amd64.tar.gz", hex.EncodeToString(sum[:]), false},
+		{"TestCase 2", srv.URL + "/go1.21.1.linux-amd64.tar.gz", hex.EncodeToString(make([]byte, sha256.Size)), true},
+		{"TestCase 3", srv.URL + "/go1.21.1.windows-amd64.zip", hex.EncodeToString(sum[:]), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "goup-stream")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			read := int64(0)
+			progress := func(totalSize int64, src io.Reader) io.Reader {
+				return &countingReader{src, &read}
+			}
+			err = StreamExtract(tt.url, tt.want, dir, progress, func(string, ...interface{}) {})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("StreamExtract() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if read != int64(len(archive)) {
+				t.Errorf("Read %d bytes, want %d", read, len(archive))
+			}
+			if err := VerifyInstall(dir, VersionInfo{Major: 1, Minor: 21, Build: 1}, "linux", "amd64"); err != nil {
+				t.Error(err)
+			}
+			if files, _ := filepath.Glob(filepath.Join(dir, "*.tar.gz")); len(files) > 0 {
+				t.Errorf("Archive written to disk: %v", files)
+			}
+		})
+	}
+}
+
+type countingReader struct {
+	r io.Reader
+	n *int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	*c.n += int64(n)
+	return n, err
+}
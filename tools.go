@@ -0,0 +1,139 @@
+package goup
+
+import (
+	"debug/buildinfo"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Tool is a Go executable found in GOBIN, described by the build
+// information embedded in it
+type Tool struct {
+	// Path is the executable
+	Path string
+	// GoVersion is the toolchain the tool is built with, zero if unknown
+	// (e.g. a development build of Go)
+	GoVersion VersionInfo
+	// Package is the path of main package, e.g. golang.org/x/tools/gopls
+	Package string
+	// Module and Version are the main module, Version is "(devel)" when built
+	// from a local checkout
+	Module  string
+	Version string
+	// Tags and CGOEnabled are build settings reused when rebuilding
+	Tags       string
+	CGOEnabled string
+}
+
+// ErrNotRebuildable is returned by Tool.Target for tools not installed by
+// go install path@version, e.g. built from a local checkout
+var ErrNotRebuildable = errors.New("Tool is not built from a published module version")
+
+// Name is the file name of the tool
+func (t Tool) Name() string {
+	return filepath.Base(t.Path)
+}
+
+// Target returns the path@version given to go install to rebuild the tool
+func (t Tool) Target() (string, error) {
+	// Local checkouts with changes get +dirty versions stamped from VCS
+	if t.Package == "" || t.Version == "" || t.Version == "(devel)" || strings.HasSuffix(t.Version, "+dirty") {
+		return "", ErrNotRebuildable
+	}
+	return t.Package + "@" + t.Version, nil
+}
+
+// BuiltBefore tells if the tool is built with a toolchain older than ver.
+// Tools built with unknown toolchain are never considered older.
+func (t Tool) BuiltBefore(ver VersionInfo) bool {
+	return t.GoVersion != VersionInfo{} && CompareVersion(t.GoVersion, ver) < 0
+}
+
+// ReadTool reads build information of executable at path
+func ReadTool(path string) (Tool, error) {
+	info, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return Tool{}, err
+	}
+	t := Tool{
+		Path:    path,
+		Package: info.Path,
+		Module:  info.Main.Path,
+		Version: info.Main.Version,
+	}
+	t.GoVersion, _ = toolchainVersion(info.GoVersion)
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "-tags":
+			t.Tags = s.Value
+		case "CGO_ENABLED":
+			t.CGOEnabled = s.Value
+		}
+	}
+	return t, nil
+}
+
+// toolchainVersion parses toolchain version recorded in build information,
+// e.g. go1.22.1 or go1.22.1 X:boringcrypto
+func toolchainVersion(s string) (VersionInfo, error) {
+	if !strings.HasPrefix(s, "go") {
+		return VersionInfo{}, errors.Errorf("Unknown toolchain version %s", s)
+	}
+	ver := s[2:]
+	if i := strings.IndexAny(ver, " -+"); i >= 0 {
+		ver = ver[:i]
+	}
+	return ExtractVersionInfo(ver)
+}
+
+// ScanTools lists Go executables in dir by name. Files without build
+// information, including executables not built by Go, are skipped.
+func ScanTools(dir string) ([]Tool, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	tools := make([]Tool, 0, len(entries))
+	for _, e := range entries {
+		if !e.Mode().IsRegular() {
+			continue
+		}
+		if t, err := ReadTool(filepath.Join(dir, e.Name())); err == nil {
+			tools = append(tools, t)
+		}
+	}
+	sort.Slice(tools, func(i, j int) bool {
+		return tools[i].Name() < tools[j].Name()
+	})
+	return tools, nil
+}
+
+// RebuildTool runs go install path@version with go executable goExe,
+// replacing the tool in its directory with the same tags and cgo setting
+func RebuildTool(goExe string, t Tool) error {
+	target, err := t.Target()
+	if err != nil {
+		return err
+	}
+	args := []string{"install"}
+	if t.Tags != "" {
+		args = append(args, "-tags", t.Tags)
+	}
+	cmd := goCommand(goExe, append(args, target)...)
+	cmd.Env = append(cmd.Env, "GOBIN="+filepath.Dir(t.Path))
+	if t.CGOEnabled != "" {
+		cmd.Env = append(cmd.Env, "CGO_ENABLED="+t.CGOEnabled)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return errors.Errorf("go install %s failed: %v\n%s", target, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
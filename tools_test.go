@@ -0,0 +1,130 @@
+package goup
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestToolchainVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    string
+		wantErr bool
+	}{
+		{"TestCase 1", "go1.22.1", "1.22.1", false},
+		{"TestCase 2", "go1.21.6 X:boringcrypto", "1.21.6", false},
+		{"TestCase 3", "go1.22.0-20240208-RC00", "1.22", false},
+		{"TestCase 4", "devel go1.23-abc123", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := toolchainVersion(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("toolchainVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.String() != tt.want {
+				t.Errorf("toolchainVersion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTool_Target(t *testing.T) {
+	tool := Tool{Package: "golang.org/x/tools/gopls", Version: "v0.15.2", GoVersion: mustVersion(t, "1.21.5")}
+	if got, err := tool.Target(); err != nil || got != "golang.org/x/tools/gopls@v0.15.2" {
+		t.Errorf("Target() = %v, %v", got, err)
+	}
+	if !tool.BuiltBefore(mustVersion(t, "1.22.0")) || tool.BuiltBefore(mustVersion(t, "1.21.5")) {
+		t.Error("BuiltBefore() compares versions incorrectly")
+	}
+	for _, v := range []string{"(devel)", "v0.0.0-20240102150405-abcdef123456+dirty"} {
+		tool.Version = v
+		if _, err := tool.Target(); err != ErrNotRebuildable {
+			t.Errorf("Target() of version %s error = %v, want ErrNotRebuildable", v, err)
+		}
+	}
+	if (Tool{}).BuiltBefore(mustVersion(t, "1.22.0")) {
+		t.Error("BuiltBefore() = true for unknown toolchain")
+	}
+}
+
+func TestScanTools(t *testing.T) {
+	goExe, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go is not on PATH")
+	}
+	dir, err := ioutil.TempDir("", "goup-tools")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "src")
+	bin := filepath.Join(dir, "bin")
+	os.MkdirAll(src, 0755)
+	os.MkdirAll(bin, 0755)
+	ioutil.WriteFile(filepath.Join(src, "go.mod"), []byte("module example.com/hello\n\ngo 1.18\n"), 0644)
+	ioutil.WriteFile(filepath.Join(src, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
+	ioutil.WriteFile(filepath.Join(bin, "script"), []byte("#!/bin/sh\n"), 0755)
+
+	cmd := exec.Command(goExe, "build", "-o", filepath.Join(bin, "hello"+exeSuffix(runtime.GOOS)), ".")
+	cmd.Dir = src
+	cmd.Env = append(os.Environ(), "GOFLAGS=", "GOTOOLCHAIN=local", "CGO_ENABLED=0")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Cannot build test tool: %v\n%s", err, out)
+	}
+
+	tools, err := ScanTools(bin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tools) != 1 {
+		t.Fatalf("ScanTools() = %+v, want 1 tool", tools)
+	}
+	tool := tools[0]
+	if tool.Package != "example.com/hello" || tool.Module != "example.com/hello" || tool.CGOEnabled != "0" {
+		t.Errorf("ScanTools() = %+v", tool)
+	}
+	if want, err := toolchainVersion(runtime.Version()); err == nil && tool.GoVersion != want {
+		t.Errorf("ScanTools() GoVersion = %v, want %v", tool.GoVersion, want)
+	}
+	if _, err := tool.Target(); err != ErrNotRebuildable {
+		t.Errorf("Target() of local build error = %v, want ErrNotRebuildable", err)
+	}
+}
+
+func TestRebuildTool(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Shell script as go executable is not supported on Windows")
+	}
+	dir, err := ioutil.TempDir("", "goup-tools")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	log := filepath.Join(dir, "log")
+	goExe := filepath.Join(dir, "go")
+	script := "#!/bin/sh\necho \"$@\" \"$GOBIN\" \"$CGO_ENABLED\" \"$GOTOOLCHAIN\" > " + log + "\n" +
+		"case \"$*\" in *broken*) echo 'module not found' >&2; exit 1;; esac\n"
+	ioutil.WriteFile(goExe, []byte(script), 0755)
+
+	tool := Tool{Path: filepath.Join(dir, "bin", "gopls"), Package: "golang.org/x/tools/gopls", Version: "v0.15.2", Tags: "netgo", CGOEnabled: "0"}
+	if err := RebuildTool(goExe, tool); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := ioutil.ReadFile(log)
+	want := "install -tags netgo golang.org/x/tools/gopls@v0.15.2 " + filepath.Join(dir, "bin") + " 0 local\n"
+	if string(got) != want {
+		t.Errorf("go invoked with %q, want %q", got, want)
+	}
+
+	tool.Package = "example.com/broken"
+	if err := RebuildTool(goExe, tool); err == nil || !strings.Contains(err.Error(), "module not found") {
+		t.Errorf("RebuildTool() error = %v, want go output", err)
+	}
+}
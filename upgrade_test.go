@@ -47,6 +47,14 @@ func TestVersionInfo_String(t *testing.T) {
 				Build: 0,
 			},
 			"1.9",
+		}, {
+			"TestCase 5",
+			VersionInfo{
+				Major: 1,
+				Minor: 21,
+				Build: 0,
+			},
+			"1.21",
 		},
 	}
 	for _, tt := range tests {
@@ -59,8 +67,6 @@ func TestVersionInfo_String(t *testing.T) {
 }
 
 func TestDownloadUrl(t *testing.T) {
-	// The following test will not pass for 100%: it will always fail in one of the case
-	// as the archive extension is on a platform-variant constant
 	type args struct {
 		version VersionInfo
 		os      string
@@ -97,6 +103,18 @@ func TestDownloadUrl(t *testing.T) {
 				"arm64",
 			},
 			"https://dl.google.com/go/go1.12beta1.linux-arm64.tar.gz",
+		}, {
+			"TestCase 3",
+			args{
+				VersionInfo{
+					Major: 1,
+					Minor: 21,
+					Build: 0,
+				},
+				"linux",
+				"amd64",
+			},
+			"https://dl.google.com/go/go1.21.0.linux-amd64.tar.gz",
 		},
 	}
 	for _, tt := range tests {
@@ -191,3 +209,28 @@ func TestExtractVersionInfo(t *testing.T) {
 		})
 	}
 }
+
+func TestCompareVersion(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want int
+	}{
+		{"TestCase 1", "1.10.3", "1.10.3", 0},
+		{"TestCase 2", "1.10.3", "1.9.7", 1},
+		{"TestCase 3", "1.11", "1.11rc2", 1},
+		{"TestCase 4", "1.11rc1", "1.11beta3", 1},
+		{"TestCase 5", "1.11rc1", "1.11rc2", -1},
+		{"TestCase 6", "1.11beta2", "1.10.8", 1},
+		{"TestCase 7", "2.0", "1.99.9", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CompareVersion(mustVersion(t, tt.a), mustVersion(t, tt.b))
+			if (got > 0) != (tt.want > 0) || (got < 0) != (tt.want < 0) {
+				t.Errorf("CompareVersion() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,465 @@
+package goup
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultVulnDB is the Go vulnerability database
+	DefaultVulnDB = "https://vuln.go.dev"
+)
+
+// Modules in the vulnerability database which describe Go itself
+var goModules = []string{"stdlib", "toolchain"}
+
+// OSVEntry is a vulnerability report in OSV format as published by the Go
+// vulnerability database. Only fields used by goup are decoded.
+type OSVEntry struct {
+	ID       string        `json:"id"`
+	Aliases  []string      `json:"aliases"`
+	Summary  string        `json:"summary"`
+	Details  string        `json:"details"`
+	Affected []OSVAffected `json:"affected"`
+}
+
+// OSVAffected lists the affected versions of one module
+type OSVAffected struct {
+	Package struct {
+		Name      string `json:"name"`
+		Ecosystem string `json:"ecosystem"`
+	} `json:"package"`
+	Ranges []struct {
+		Type   string `json:"type"`
+		Events []struct {
+			Introduced string `json:"introduced,omitempty"`
+			Fixed      string `json:"fixed,omitempty"`
+		} `json:"events"`
+	} `json:"ranges"`
+	EcosystemSpecific struct {
+		Imports []struct {
+			Path string `json:"path"`
+		} `json:"imports"`
+	} `json:"ecosystem_specific"`
+}
+
+// Advisory is a vulnerability affecting a specific Go version
+type Advisory struct {
+	ID       string
+	Aliases  []string
+	Summary  string
+	Packages []string
+	// Fixed is the first version on the affected release branch with the fix,
+	// only valid when HasFix is true
+	Fixed  VersionInfo
+	HasFix bool
+}
+
+// LoadVulnDB reads the stdlib and toolchain entries of a Go vulnerability
+// database. source can be an HTTP(S) URL of the database, a directory holding
+// an offline copy or the zip export (vulndb.zip). Empty source means
+// DefaultVulnDB. The export of a remote database is cached in VulnDBCacheDir.
+func LoadVulnDB(source string) ([]OSVEntry, error) {
+	if source == "" {
+		source = DefaultVulnDB
+	}
+	switch {
+	case strings.HasPrefix(source, "http://"), strings.HasPrefix(source, "https://"):
+		return loadVulnDBHTTP(strings.TrimSuffix(source, "/"))
+	case strings.HasPrefix(source, "file://"):
+		source = filepath.FromSlash(strings.TrimPrefix(source, "file://"))
+	}
+	if strings.HasSuffix(strings.ToLower(source), ".zip") {
+		return loadVulnDBZip(source)
+	}
+	return loadVulnDBDir(source)
+}
+
+type modulesIndex []struct {
+	Path  string `json:"path"`
+	Vulns []struct {
+		ID string `json:"id"`
+	} `json:"vulns"`
+}
+
+// vulnDBWorkers is the number of entries fetched concurrently when the
+// database has no zip export
+const vulnDBWorkers = 8
+
+// VulnDBCacheDir is where zip exports of remote vulnerability databases are
+// cached
+func VulnDBCacheDir() string {
+	return filepath.Join(HomeDir(), "vulndb")
+}
+
+// exportFetchError is a failure to fetch or decode the zip export of a
+// database, after which its entries are fetched one by one
+type exportFetchError struct {
+	err error
+}
+
+func (e *exportFetchError) Error() string {
+	return e.err.Error()
+}
+
+// fetchError marks err as exportFetchError unless it comes from local files
+func fetchError(err error) error {
+	switch err.(type) {
+	case nil, *os.PathError, *os.LinkError:
+		return err
+	}
+	return &exportFetchError{err}
+}
+
+// loadVulnDBHTTP reads the zip export of the database, cached until the
+// database is modified, or every entry one by one if the export cannot be
+// fetched. Errors caching the export are returned.
+func loadVulnDBHTTP(baseURL string) ([]OSVEntry, error) {
+	entries, err := loadVulnDBExport(baseURL)
+	if _, ok := errors.Cause(err).(*exportFetchError); ok {
+		// Mirrors may not publish the export
+		return loadVulnDBEntries(baseURL)
+	}
+	return entries, err
+}
+
+func loadVulnDBExport(baseURL string) ([]OSVEntry, error) {
+	var db struct {
+		Modified time.Time `json:"modified"`
+	}
+	if err := getJSON(baseURL+"/index/db.json", &db); err != nil {
+		return nil, &exportFetchError{err}
+	}
+	sum := sha256.Sum256([]byte(baseURL))
+	cache := filepath.Join(VulnDBCacheDir(), hex.EncodeToString(sum[:8])+".zip")
+	// Modification time of the cached export is that of the database
+	if fi, err := os.Stat(cache); err != nil || fi.ModTime().Unix() != db.Modified.Unix() {
+		if err := downloadVulnDBExport(baseURL+"/vulndb.zip", cache); err != nil {
+			return nil, err
+		}
+		if err := os.Chtimes(cache, db.Modified, db.Modified); err != nil {
+			return nil, err
+		}
+	}
+	entries, err := loadVulnDBZip(cache)
+	if err != nil {
+		return nil, fetchError(errors.Cause(err))
+	}
+	return entries, nil
+}
+
+// downloadVulnDBExport saves url to file, replacing it only when complete
+func downloadVulnDBExport(url, file string) error {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return &exportFetchError{err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return &exportFetchError{errors.New("Error code: " + strconv.Itoa(resp.StatusCode))}
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(file), "."+filepath.Base(file))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tmp, resp.Body)
+	err = fetchError(err)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), file)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return errors.Wrap(err, "Cannot download vulnerability database")
+	}
+	return nil
+}
+
+func loadVulnDBEntries(baseURL string) ([]OSVEntry, error) {
+	var index modulesIndex
+	if err := getJSON(baseURL+"/index/modules.json", &index); err != nil {
+		return nil, errors.Wrap(err, "Cannot read vulnerability database index")
+	}
+	ids := make([]string, 0)
+	for _, mod := range index {
+		if !isGoModule(mod.Path) {
+			continue
+		}
+		for _, v := range mod.Vulns {
+			ids = append(ids, v.ID)
+		}
+	}
+
+	entries := make([]OSVEntry, len(ids))
+	errs := make([]error, len(ids))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < vulnDBWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				if err := getJSON(baseURL+"/ID/"+ids[j]+".json", &entries[j]); err != nil {
+					errs[j] = errors.Wrapf(err, "Cannot read vulnerability %s", ids[j])
+				}
+			}
+		}()
+	}
+	for j := range ids {
+		jobs <- j
+	}
+	close(jobs)
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return dedupEntries(entries), nil
+}
+
+func getJSON(url string, v interface{}) error {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return errors.New("Error code: " + strconv.Itoa(resp.StatusCode))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func loadVulnDBDir(dir string) ([]OSVEntry, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "ID", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("No vulnerability entries found in %s", dir)
+	}
+	entries := make([]OSVEntry, 0)
+	for _, f := range files {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		var entry OSVEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return nil, errors.Wrapf(err, "Cannot parse %s", f)
+		}
+		if affectsGo(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func loadVulnDBZip(file string) ([]OSVEntry, error) {
+	zr, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot open vulnerability database archive")
+	}
+	defer zr.Close()
+	entries := make([]OSVEntry, 0)
+	for _, f := range zr.File {
+		if path.Ext(f.Name) != ".json" || path.Base(path.Dir(f.Name)) != "ID" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		var entry OSVEntry
+		err = json.NewDecoder(rc).Decode(&entry)
+		rc.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "Cannot parse %s", f.Name)
+		}
+		if affectsGo(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func isGoModule(name string) bool {
+	for _, m := range goModules {
+		if name == m {
+			return true
+		}
+	}
+	return false
+}
+
+func affectsGo(entry OSVEntry) bool {
+	for _, a := range entry.Affected {
+		if isGoModule(a.Package.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func dedupEntries(entries []OSVEntry) []OSVEntry {
+	seen := make(map[string]bool)
+	result := make([]OSVEntry, 0, len(entries))
+	for _, e := range entries {
+		if !seen[e.ID] {
+			seen[e.ID] = true
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// AffectingAdvisories returns the advisories in entries affecting version,
+// sorted by ID
+func AffectingAdvisories(entries []OSVEntry, version VersionInfo) []Advisory {
+	advisories := make([]Advisory, 0)
+	for _, entry := range entries {
+		adv := Advisory{ID: entry.ID, Aliases: entry.Aliases, Summary: entry.Summary}
+		affected, unfixed := false, false
+		for _, a := range entry.Affected {
+			if !isGoModule(a.Package.Name) {
+				continue
+			}
+			fixed, hasFix, ok := affectedRange(a, version)
+			if !ok {
+				continue
+			}
+			affected = true
+			if len(a.EcosystemSpecific.Imports) == 0 {
+				adv.Packages = append(adv.Packages, a.Package.Name)
+			}
+			for _, imp := range a.EcosystemSpecific.Imports {
+				adv.Packages = append(adv.Packages, imp.Path)
+			}
+			if !hasFix {
+				unfixed = true
+			} else if CompareVersion(fixed, adv.Fixed) > 0 {
+				adv.Fixed = fixed
+			}
+		}
+		adv.HasFix = affected && !unfixed
+		if !adv.HasFix {
+			adv.Fixed = VersionInfo{}
+		}
+		if affected {
+			advisories = append(advisories, adv)
+		}
+	}
+	sort.Slice(advisories, func(i, j int) bool {
+		return advisories[i].ID < advisories[j].ID
+	})
+	return advisories
+}
+
+// affectedRange checks if version falls into one of the SEMVER ranges and
+// returns the version fixing that range
+func affectedRange(a OSVAffected, version VersionInfo) (fixed VersionInfo, hasFix bool, affected bool) {
+	for _, r := range a.Ranges {
+		if r.Type != "SEMVER" {
+			continue
+		}
+		var introduced *VersionInfo
+		for _, ev := range r.Events {
+			if ev.Introduced != "" {
+				vi := VersionInfo{}
+				if ev.Introduced != "0" {
+					var err error
+					vi, err = ParseSemver(ev.Introduced)
+					if err != nil {
+						introduced = nil
+						continue
+					}
+				}
+				introduced = &vi
+				continue
+			}
+			if ev.Fixed == "" || introduced == nil {
+				continue
+			}
+			fix, err := ParseSemver(ev.Fixed)
+			if err != nil {
+				continue
+			}
+			if CompareVersion(version, *introduced) >= 0 && CompareVersion(version, fix) < 0 {
+				return fix, true, true
+			}
+			introduced = nil
+		}
+		if introduced != nil && CompareVersion(version, *introduced) >= 0 {
+			return VersionInfo{}, false, true
+		}
+	}
+	return VersionInfo{}, false, false
+}
+
+// MinimumFixedVersion returns the lowest version fixing all advisories, which
+// is the highest of their fixed versions. The bool result is false if any
+// advisory has no fix yet or there is none.
+func MinimumFixedVersion(advisories []Advisory) (VersionInfo, bool) {
+	var min VersionInfo
+	for _, adv := range advisories {
+		if !adv.HasFix {
+			return VersionInfo{}, false
+		}
+		if CompareVersion(adv.Fixed, min) > 0 {
+			min = adv.Fixed
+		}
+	}
+	return min, len(advisories) > 0
+}
+
+// ParseSemver converts version used by the vulnerability database (e.g.
+// 1.21.2, 1.21.0-rc.2) to VersionInfo. The "-0" pre-release suffix denotes the
+// earliest pre-release of a version and is treated as beta 0.
+func ParseSemver(semver string) (VersionInfo, error) {
+	semver = strings.TrimPrefix(semver, "v")
+	pre := ""
+	if i := strings.Index(semver, "-"); i >= 0 {
+		semver, pre = semver[:i], semver[i+1:]
+	}
+	vi, err := ExtractVersionInfo(semver)
+	if err != nil {
+		return VersionInfo{}, err
+	}
+	switch {
+	case pre == "":
+	case pre == "0":
+		vi.Beta = true
+	case strings.HasPrefix(pre, "rc."):
+		vi.RC = true
+		vi.RCVersion, err = strconv.Atoi(pre[len("rc."):])
+	case strings.HasPrefix(pre, "beta."):
+		vi.Beta = true
+		vi.BetaVersion, err = strconv.Atoi(pre[len("beta."):])
+	default:
+		err = fmt.Errorf("Unknown pre-release %q", pre)
+	}
+	if err != nil {
+		return VersionInfo{}, errors.Wrapf(err, "Cannot parse version %s", semver)
+	}
+	return vi, nil
+}
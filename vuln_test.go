@@ -0,0 +1,199 @@
+package goup
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testVulnDB = "testdata/vulndb"
+
+func zipTestVulnDB(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "goup-vulndb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	zipPath := filepath.Join(dir, "vulndb.zip")
+	f, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	files, _ := filepath.Glob(filepath.Join(testVulnDB, "*", "*.json"))
+	for _, file := range files {
+		rel, _ := filepath.Rel(testVulnDB, file)
+		w, err := zw.Create(filepath.ToSlash(rel))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := ioutil.ReadFile(file)
+		w.Write(data)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return zipPath
+}
+
+func TestLoadVulnDB(t *testing.T) {
+	srv := httptest.NewServer(http.FileServer(http.Dir(testVulnDB)))
+	defer srv.Close()
+	zipPath := zipTestVulnDB(t)
+	defer os.RemoveAll(filepath.Dir(zipPath))
+
+	want := []string{"GO-2023-2041", "GO-2023-2043", "GO-2024-2887"}
+	for _, source := range []string{testVulnDB, zipPath, srv.URL} {
+		t.Run(source, func(t *testing.T) {
+			entries, err := LoadVulnDB(source)
+			if err != nil {
+				t.Fatalf("LoadVulnDB() error = %v", err)
+			}
+			got := make(map[string]bool)
+			for _, e := range entries {
+				got[e.ID] = true
+			}
+			if len(got) != len(want) {
+				t.Errorf("LoadVulnDB() returns %v, want %v", got, want)
+			}
+			for _, id := range want {
+				if !got[id] {
+					t.Errorf("LoadVulnDB() missing %s", id)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadVulnDBExport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goup-vulndb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	setGoupHome(t, dir)
+	zipPath := zipTestVulnDB(t)
+	defer os.RemoveAll(filepath.Dir(zipPath))
+
+	var modified string
+	requests := make(map[string]int)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		switch r.URL.Path {
+		case "/index/db.json":
+			w.Write([]byte(`{"modified": "` + modified + `"}`))
+		case "/vulndb.zip":
+			http.ServeFile(w, r, zipPath)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name          string
+		modified      string
+		wantDownloads int
+	}{
+		{"TestCase 1", "2024-06-04T20:15:04Z", 1},
+		{"TestCase 2", "2024-06-04T20:15:04Z", 1},
+		{"TestCase 3", "2024-06-05T08:00:00Z", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modified = tt.modified
+			entries, err := LoadVulnDB(srv.URL)
+			if err != nil {
+				t.Fatalf("LoadVulnDB() error = %v", err)
+			}
+			if len(entries) != 3 {
+				t.Errorf("LoadVulnDB() returns %d entries, want 3", len(entries))
+			}
+			if requests["/vulndb.zip"] != tt.wantDownloads {
+				t.Errorf("Export downloaded %d times, want %d", requests["/vulndb.zip"], tt.wantDownloads)
+			}
+		})
+	}
+	// Cache which cannot be written is an error, not a missing export
+	os.RemoveAll(VulnDBCacheDir())
+	ioutil.WriteFile(VulnDBCacheDir(), nil, 0644)
+	modified = "2024-06-06T08:00:00Z"
+	if _, err := LoadVulnDB(srv.URL); err == nil {
+		t.Error("LoadVulnDB() succeeded without a writable cache")
+	}
+	if len(requests) != 2 {
+		t.Errorf("Requests other than index and export: %v", requests)
+	}
+}
+
+func TestAffectingAdvisories(t *testing.T) {
+	entries, err := LoadVulnDB(testVulnDB)
+	if err != nil {
+		t.Fatalf("LoadVulnDB() error = %v", err)
+	}
+	tests := []struct {
+		name         string
+		version      string
+		wantIDs      []string
+		wantMinFixed string
+	}{
+		{"TestCase 1", "1.21.0", []string{"GO-2023-2041", "GO-2023-2043", "GO-2024-2887"}, "1.21.11"},
+		{"TestCase 2", "1.21.1", []string{"GO-2023-2041", "GO-2024-2887"}, "1.21.11"},
+		{"TestCase 3", "1.20.8", []string{"GO-2023-2041", "GO-2024-2887"}, "1.21.11"},
+		{"TestCase 4", "1.22.1", []string{"GO-2024-2887"}, "1.22.4"},
+		{"TestCase 5", "1.22.4", nil, ""},
+		{"TestCase 6", "1.21rc2", []string{"GO-2023-2041", "GO-2023-2043", "GO-2024-2887"}, "1.21.11"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			advisories := AffectingAdvisories(entries, mustVersion(t, tt.version))
+			ids := make([]string, 0)
+			for _, adv := range advisories {
+				ids = append(ids, adv.ID)
+			}
+			if len(ids) != len(tt.wantIDs) || len(ids) > 0 && !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("AffectingAdvisories() = %v, want %v", ids, tt.wantIDs)
+			}
+			minFixed, ok := MinimumFixedVersion(advisories)
+			if ok != (tt.wantMinFixed != "") {
+				t.Fatalf("MinimumFixedVersion() ok = %v", ok)
+			}
+			if ok && minFixed != mustVersion(t, tt.wantMinFixed) {
+				t.Errorf("MinimumFixedVersion() = %v, want %v", minFixed, tt.wantMinFixed)
+			}
+		})
+	}
+}
+
+func TestParseSemver(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    string
+		want    VersionInfo
+		wantErr bool
+	}{
+		{"TestCase 1", "1.21.2", VersionInfo{Major: 1, Minor: 21, Build: 2}, false},
+		{"TestCase 2", "v1.21.0-rc.2", VersionInfo{Major: 1, Minor: 21, RC: true, RCVersion: 2}, false},
+		{"TestCase 3", "1.21.0-0", VersionInfo{Major: 1, Minor: 21, Beta: true}, false},
+		{"TestCase 4", "1.11.0-beta.1", VersionInfo{Major: 1, Minor: 11, Beta: true, BetaVersion: 1}, false},
+		{"TestCase 5", "1.21.0-alpha", VersionInfo{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSemver(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseSemver() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseSemver() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}